@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/NubeIO/platform/services/limits"
+	"github.com/NubeIO/platform/services/secrets"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [--key <key file>] [--nice <n>] [--max-open-files <n>] -- <command> [args...]",
+	Short: "run a command with its encrypted env vars decrypted and its limits applied",
+	Long:  "the hosts start through it, so their secrets are only ever decrypted in memory and their limits are set before the exec",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   execCommand,
+}
+
+var flgExec struct {
+	key          string
+	nice         int
+	maxOpenFiles uint64
+}
+
+func execCommand(cmd *cobra.Command, args []string) {
+	env := os.Environ()
+	if flgExec.key != "" {
+		// the key is never created here, a new one couldn't decrypt anything and would replace the one of the platform
+		if _, err := os.Stat(flgExec.key); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		var err error
+		if env, err = secrets.New(flgExec.key).DecryptEnv(env); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	// the nice level is per thread, the exec has to happen on the thread it was set on
+	runtime.LockOSThread()
+	if err := limits.Apply(&limits.Limits{Nice: flgExec.nice, MaxOpenFiles: flgExec.maxOpenFiles}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	command, err := exec.LookPath(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	err = syscall.Exec(command, args, env)
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func init() {
+	RootCmd.AddCommand(execCmd)
+	execCmd.Flags().StringVarP(&flgExec.key, "key", "", "", "key file of the secrets")
+	execCmd.Flags().IntVarP(&flgExec.nice, "nice", "", 0, "nice level of the command")
+	execCmd.Flags().Uint64VarP(&flgExec.maxOpenFiles, "max-open-files", "", 0, "open files rlimit of the command")
+}
@@ -32,6 +32,15 @@ func Setup(rootCmd_ *cobra.Command) error {
 	viper.SetDefault("database.name", "data.db")
 	viper.SetDefault("server.log.store", false)
 	viper.SetDefault("gin.log.store", false)
+	viper.SetDefault("hosts.db.backups", 5)
+	viper.SetDefault("hosts.log.max_size_mb", 10)
+	viper.SetDefault("hosts.log.max_backups", 3)
+	viper.SetDefault("hosts.events.max", 1000)
+	viper.SetDefault("hosts.import.max_size_mb", 1024)
+	viper.SetDefault("uploads.expiry_hours", 24)
+	viper.SetDefault("trash.enabled", true)
+	viper.SetDefault("trash.max_age_days", 30)
+	viper.SetDefault("trash.max_size_mb", 1024)
 	Config = configuration
 	return nil
 }
@@ -55,6 +64,10 @@ func (conf *Configuration) GetAbsConfigDir() string {
 	return path.Join(conf.GetGlobalDir(), conf.getConfigDir())
 }
 
+func (conf *Configuration) GetRootDir() string {
+	return RootCmd.PersistentFlags().Lookup("root-dir").Value.String()
+}
+
 func (conf *Configuration) GetGlobalDir() string {
 	rootDir := RootCmd.PersistentFlags().Lookup("root-dir").Value.String()
 	appDir := RootCmd.PersistentFlags().Lookup("app-dir").Value.String()
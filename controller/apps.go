@@ -11,18 +11,20 @@ import (
 )
 
 func (inst *Controller) UploadAddOnAppStore(c *gin.Context) {
-	file, err := c.FormFile("file")
-	if err != nil {
-		responseHandler(nil, err, c)
-		return
-	}
 	m := &dto.Upload{
 		Name:    c.Query("name"),
 		Version: c.Query("version"),
 		Arch:    c.Query("arch"),
-		File:    file,
+	}
+	done, err := inst.storeUpload(c, m)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
 	}
 	data, err := inst.Store.UploadAddOnAppStore(m)
+	if err == nil {
+		done()
+	}
 	responseHandler(data, err, c)
 }
 
@@ -65,15 +67,16 @@ func (inst *Controller) GetModulesStoreModules(c *gin.Context) {
 }
 
 func (inst *Controller) UploadModuleStoreModule(c *gin.Context) {
-	file, err := c.FormFile("file")
+	m := &dto.Upload{}
+	done, err := inst.storeUpload(c, m)
 	if err != nil {
 		responseHandler(nil, err, c)
 		return
 	}
-	m := &dto.Upload{
-		File: file,
-	}
 	data, err := inst.Store.UploadModuleStoreModule(m)
+	if err == nil {
+		done()
+	}
 	responseHandler(data, err, c)
 }
 
@@ -83,14 +86,15 @@ func (inst *Controller) GetPluginsStorePlugins(c *gin.Context) {
 }
 
 func (inst *Controller) UploadPluginStorePlugin(c *gin.Context) {
-	file, err := c.FormFile("file")
+	m := &dto.Upload{}
+	done, err := inst.storeUpload(c, m)
 	if err != nil {
 		responseHandler(nil, err, c)
 		return
 	}
-	m := &dto.Upload{
-		File: file,
-	}
 	data, err := inst.Store.UploadPluginStorePlugin(m)
+	if err == nil {
+		done()
+	}
 	responseHandler(data, err, c)
 }
@@ -6,8 +6,21 @@ import (
 	"github.com/NubeIO/platform/config"
 	"github.com/NubeIO/platform/model"
 	"github.com/NubeIO/platform/services/appstore"
+	"github.com/NubeIO/platform/services/artifact"
+	"github.com/NubeIO/platform/services/hostdb"
+	"github.com/NubeIO/platform/services/hostevents"
+	"github.com/NubeIO/platform/services/hostlog"
 	"github.com/NubeIO/platform/services/info"
+	"github.com/NubeIO/platform/services/probe"
+	"github.com/NubeIO/platform/services/restart"
+	"github.com/NubeIO/platform/services/rubixregistry"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/NubeIO/platform/services/secrets"
+	"github.com/NubeIO/platform/services/supervisor"
 	systeminfo "github.com/NubeIO/platform/services/system"
+	"github.com/NubeIO/platform/services/trash"
+	"github.com/NubeIO/platform/services/unitfile"
+	"github.com/NubeIO/platform/services/uploads"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"net/url"
@@ -19,11 +32,26 @@ type Controller struct {
 	SystemCtl  *systemctl.SystemCtl
 	FileMode   int
 	Instances  map[string]*Instance
+	DB         *hostdb.Store
 	Lock       sync.Mutex
 	Config     *config.Configuration
 	SystemInfo systeminfo.System
 	Networking *info.System
 	Store      *appstore.Store
+	Supervisor *supervisor.Supervisor
+	Units      *unitfile.Manager
+	Probes     *probe.Monitor
+	Logs       *hostlog.Manager
+	Events     *hostevents.Manager
+	Restarts   *restart.Scheduler
+	Registry   *rubixregistry.RubixRegistry
+	Artifacts  *artifact.Fetcher
+	Secrets    *secrets.Box
+	Sandbox    *sandbox.Sandbox
+	Uploads    *uploads.Manager
+	Trash      *trash.Manager
+
+	MaxImportBytes int64 // size limit of the host bundles
 }
 
 type Response struct {
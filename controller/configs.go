@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/services/configfile"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+type AppConfig struct {
+	FilePath string          `json:"filePath"`
+	Format   string          `json:"format"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// appConfigPath finds the config under the app data config dir, e.g. <root_dir>/rubix-wires/config/config.yml,
+// the response is already sent when it returns false
+func (inst *Controller) appConfigPath(c *gin.Context, access sandbox.Access) (string, bool) {
+	app, configName := c.Param("app"), c.Param("configName")
+	for _, name := range []string{app, configName} {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			responseHandler(nil, errors.New(fmt.Sprintf("invalid app or config name %s", name)), c)
+			return "", false
+		}
+	}
+	return inst.resolvePath(c, path.Join(inst.Store.Installer.GetAppDataConfigPath(app), configName), access)
+}
+
+func configStatus(err error) int {
+	if errors.Is(err, os.ErrNotExist) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// GetAppConfig returns a json, yaml or .env config as json
+func (inst *Controller) GetAppConfig(c *gin.Context) {
+	filePath, ok := inst.appConfigPath(c, sandbox.Read)
+	if !ok {
+		return
+	}
+	data, format, err := configfile.Read(filePath)
+	if err != nil {
+		responseHandler(nil, err, c, configStatus(err))
+		return
+	}
+	responseHandler(AppConfig{FilePath: filePath, Format: format, Data: data}, nil, c)
+}
+
+// PatchAppConfig applies an RFC 7386 merge-patch, the file keeps its format, key order & comments
+// curl -X PATCH http://localhost:1661/api/configs/rubix-wires/config.yml -d '{"server":{"port":1661},"debug":null}'
+func (inst *Controller) PatchAppConfig(c *gin.Context) {
+	filePath, ok := inst.appConfigPath(c, sandbox.Write)
+	if !ok {
+		return
+	}
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	format, err := configfile.Format(filePath)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	data, err := configfile.Patch(filePath, patch)
+	if err != nil {
+		responseHandler(nil, err, c, configStatus(err))
+		return
+	}
+	responseHandler(AppConfig{FilePath: filePath, Format: format, Data: data}, nil, c)
+}
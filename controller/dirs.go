@@ -4,9 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/NubeIO/lib-files/fileutils"
+	"github.com/NubeIO/platform/logger"
 	"github.com/NubeIO/platform/model"
+	"github.com/NubeIO/platform/services/archive"
+	"github.com/NubeIO/platform/services/sandbox"
 	"github.com/gin-gonic/gin"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 type DirExistence struct {
@@ -15,7 +21,10 @@ type DirExistence struct {
 }
 
 func (inst *Controller) DirExists(c *gin.Context) {
-	path := c.Query("path")
+	path, ok := inst.resolvePath(c, c.Query("path"), sandbox.Read)
+	if !ok {
+		return
+	}
 	exists := fileutils.DirExists(path)
 	dirExistence := DirExistence{Path: path, Exists: exists}
 	responseHandler(dirExistence, nil, c)
@@ -27,6 +36,59 @@ func (inst *Controller) CreateDir(c *gin.Context) {
 		responseHandler(nil, errors.New("path can not be empty"), c)
 		return
 	}
+	path, ok := inst.resolvePath(c, path, sandbox.Write)
+	if !ok {
+		return
+	}
 	err := os.MkdirAll(path, os.FileMode(inst.FileMode))
 	responseHandler(model.Message{Message: fmt.Sprintf("created directory: %s", path)}, err, c)
 }
+
+func splitGlobs(value string) []string {
+	var globs []string
+	for _, glob := range strings.Split(value, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			globs = append(globs, glob)
+		}
+	}
+	return globs
+}
+
+// DownloadDir streams the dir as an archive, entries outside the allowed roots are left out
+// curl "http://localhost:1661/api/dirs/download?path=/data/flow-framework&format=tar.gz&include=*.db,*.yaml&exclude=logs" -o flow-framework.tar.gz
+func (inst *Controller) DownloadDir(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		responseHandler(nil, errors.New("path can not be empty, try /data/flow-framework"), c)
+		return
+	}
+	path, ok := inst.resolvePath(c, path, sandbox.Read)
+	if !ok {
+		return
+	}
+	if !fileutils.DirExists(path) {
+		responseHandler(nil, errors.New(fmt.Sprintf("dir not found %s", path)), c, http.StatusNotFound)
+		return
+	}
+	format := c.DefaultQuery("format", archive.Zip)
+	if format != archive.Zip && format != archive.TarGz {
+		responseHandler(nil, errors.New(fmt.Sprintf("format must be %s or %s", archive.Zip, archive.TarGz)), c)
+		return
+	}
+	filter := &archive.Filter{
+		Include: splitGlobs(c.Query("include")),
+		Exclude: splitGlobs(c.Query("exclude")),
+		Skip:    inst.Sandbox.Hidden,
+	}
+	if err := filter.Validate(); err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	c.Header("Content-Type", archive.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s.%s", filepath.Base(path), format)))
+	c.Status(http.StatusOK)
+	// the status is already sent, a failure can only cut the archive short
+	if err := archive.Write(c.Writer, path, format, filter); err != nil {
+		logger.Logger.Errorf("failed to stream the archive of %s: %s", path, err.Error())
+	}
+}
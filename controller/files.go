@@ -4,15 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"github.com/NubeIO/lib-files/fileutils"
+	"github.com/NubeIO/platform/dto"
 	"github.com/NubeIO/platform/model"
+	"github.com/NubeIO/platform/services/filelist"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/NubeIO/platform/utils/checksum"
 	"github.com/gin-gonic/gin"
 	"io/fs"
-	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,51 +31,106 @@ type UploadResponse struct {
 	File        string `json:"file"`
 	Size        string `json:"size"`
 	UploadTime  string `json:"uploadTime"`
+	Checksum    string `json:"checksum,omitempty"`
+}
+
+type FileChecksum struct {
+	File     string `json:"file"`
+	Algo     string `json:"algo"`
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
 }
 
 func (inst *Controller) FileExists(c *gin.Context) {
 	file := c.Query("file")
-	exists := fileutils.FileExists(file)
+	resolved, ok := inst.resolvePath(c, file, sandbox.Read)
+	if !ok {
+		return
+	}
+	exists := fileutils.FileExists(resolved)
 	fileExistence := FileExistence{File: file, Exists: exists}
 	responseHandler(fileExistence, nil, c)
 }
 
-func (inst *Controller) WalkFile(c *gin.Context) {
-	path_ := c.Query("path")
-	files := make([]string, 0)
-	err := filepath.WalkDir(path_, func(p string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+// fileListOptions reads ?max_depth=&glob=&regex=&type=file|dir&sort=name|path|size|modified&order=asc|desc&offset=&limit=
+func fileListOptions(c *gin.Context) (*filelist.Options, bool, error) {
+	o := &filelist.Options{
+		Glob:  c.Query("glob"),
+		Regex: c.Query("regex"),
+		Type:  c.Query("type"),
+		Sort:  c.Query("sort"),
+		Desc:  c.Query("order") == "desc",
+	}
+	for key, value := range map[string]*int{"max_depth": &o.MaxDepth, "offset": &o.Offset, "limit": &o.Limit} {
+		if raw := c.Query(key); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, false, errors.New(fmt.Sprintf("%s must be a number", key))
+			}
+			*value = n
 		}
-		files = append(files, p)
-		return nil
-	})
-	responseHandler(files, err, c)
+	}
+	paginated := c.Query("offset") != "" || c.Query("limit") != ""
+	return o, paginated, nil
 }
 
-func (inst *Controller) ListFiles(c *gin.Context) {
-	files, err := inst.listFiles(c.Query("path"))
-	responseHandler(files, err, c)
+func paginate(data interface{}, total int, o *filelist.Options) *dto.PaginationResponse {
+	offset, limit := o.Offset, o.Limit
+	return &dto.PaginationResponse{Total: int64(total), Offset: &offset, Limit: &limit, Data: data}
 }
 
-func (inst *Controller) listFiles(_path string) ([]fileutils.FileDetails, error) {
-	fileInfo, err := os.Stat(_path)
-	dirContent := make([]fileutils.FileDetails, 0)
+// WalkFile returns the paths under the tree, ?details=true returns the metadata too
+func (inst *Controller) WalkFile(c *gin.Context) {
+	path_, ok := inst.resolvePath(c, c.Query("path"), sandbox.Read)
+	if !ok {
+		return
+	}
+	o, paginated, err := fileListOptions(c)
 	if err != nil {
-		return nil, err
+		responseHandler(nil, err, c)
+		return
 	}
-	if fileInfo.IsDir() {
-		files, err := ioutil.ReadDir(_path)
-		if err != nil {
-			return nil, err
-		}
-		for _, file := range files {
-			dirContent = append(dirContent, fileutils.FileDetails{Name: file.Name(), IsDir: file.IsDir()})
+	o.Skip = inst.Sandbox.Hidden
+	entries, total, err := filelist.Walk(path_, o)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	var data interface{} = entries
+	if c.Query("details") != "true" {
+		files := make([]string, 0)
+		for _, entry := range entries {
+			files = append(files, entry.Path)
 		}
-	} else {
-		return nil, errors.New("it needs to be a directory, found a file")
+		data = files
 	}
-	return dirContent, nil
+	if paginated {
+		data = paginate(data, total, o)
+	}
+	responseHandler(data, nil, c)
+}
+
+func (inst *Controller) ListFiles(c *gin.Context) {
+	path_, ok := inst.resolvePath(c, c.Query("path"), sandbox.Read)
+	if !ok {
+		return
+	}
+	o, paginated, err := fileListOptions(c)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	o.Skip = inst.Sandbox.Hidden
+	entries, total, err := filelist.List(path_, o)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	if paginated {
+		responseHandler(paginate(entries, total, o), nil, c)
+		return
+	}
+	responseHandler(entries, nil, c)
 }
 
 func (inst *Controller) CreateFile(c *gin.Context) {
@@ -79,6 +139,10 @@ func (inst *Controller) CreateFile(c *gin.Context) {
 		responseHandler(nil, errors.New("file can not be empty"), c)
 		return
 	}
+	file, ok := inst.resolvePath(c, file, sandbox.Write)
+	if !ok {
+		return
+	}
 	_, err := fileutils.CreateFile(file, os.FileMode(inst.FileMode))
 	responseHandler(model.Message{Message: fmt.Sprintf("created file: %s", file)}, err, c)
 }
@@ -90,6 +154,14 @@ func (inst *Controller) CopyFile(c *gin.Context) {
 		responseHandler(nil, errors.New("from and to names can not be empty"), c)
 		return
 	}
+	from, ok := inst.resolveTree(c, from, sandbox.Read)
+	if !ok {
+		return
+	}
+	to, ok = inst.resolvePath(c, to, sandbox.Write)
+	if !ok {
+		return
+	}
 	err := fileutils.Copy(from, to)
 	responseHandler(model.Message{Message: "copied successfully"}, err, c)
 }
@@ -101,6 +173,14 @@ func (inst *Controller) RenameFile(c *gin.Context) {
 		responseHandler(nil, errors.New("old_path & new_path names can not be empty"), c)
 		return
 	}
+	oldPath, ok := inst.resolveTree(c, oldPath, sandbox.Remove)
+	if !ok {
+		return
+	}
+	newPath, ok = inst.resolvePath(c, newPath, sandbox.Write)
+	if !ok {
+		return
+	}
 	err := os.Rename(oldPath, newPath)
 	responseHandler(model.Message{Message: "renamed successfully"}, err, c)
 }
@@ -116,6 +196,14 @@ func (inst *Controller) MoveFile(c *gin.Context) {
 		responseHandler(nil, errors.New("from and to names are same"), c)
 		return
 	}
+	from, ok := inst.resolveTree(c, from, sandbox.Remove)
+	if !ok {
+		return
+	}
+	to, ok = inst.resolvePath(c, to, sandbox.Write)
+	if !ok {
+		return
+	}
 	err := os.Rename(from, to)
 	responseHandler(model.Message{Message: "moved successfully"}, err, c)
 }
@@ -123,33 +211,80 @@ func (inst *Controller) MoveFile(c *gin.Context) {
 func (inst *Controller) DownloadFile(c *gin.Context) {
 	path_ := c.Query("path")
 	fileName := c.Query("file")
-	c.FileAttachment(fmt.Sprintf("%s/%s", path_, fileName), fileName)
+	file, ok := inst.resolvePath(c, fmt.Sprintf("%s/%s", path_, fileName), sandbox.Read)
+	if !ok {
+		return
+	}
+	c.FileAttachment(file, fileName)
+}
+
+// ChecksumFile hashes the file as a stream, ?algo=sha256|sha1|md5
+func (inst *Controller) ChecksumFile(c *gin.Context) {
+	file := c.Query("file")
+	if file == "" {
+		responseHandler(nil, errors.New("file can not be empty"), c)
+		return
+	}
+	file, ok := inst.resolvePath(c, file, sandbox.Read)
+	if !ok {
+		return
+	}
+	algo := strings.ToLower(c.DefaultQuery("algo", checksum.SHA256))
+	info, err := os.Stat(file)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	if info.IsDir() {
+		responseHandler(nil, errors.New("it needs to be a file, found a directory"), c)
+		return
+	}
+	digest, err := checksum.File(file, algo)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	responseHandler(FileChecksum{File: file, Algo: algo, Checksum: digest, Size: info.Size()}, nil, c)
 }
 
 // UploadFile
 // curl -X POST http://localhost:1661/api/files/upload?destination=/data/ -F "file=@/home/user/Downloads/bios-master.zip" -H "Content-Type: multipart/form-data"
+// or with a finalized chunked upload: /api/files/upload?destination=/data/&upload_id=<id>
+// ?checksum=sha256:<hex> rejects the upload when it doesn't match
 func (inst *Controller) UploadFile(c *gin.Context) {
 	now := time.Now()
 	destination := c.Query("destination")
-	file, err := c.FormFile("file")
+	upload := &dto.Upload{}
+	done, err := inst.storeUpload(c, upload)
 	resp := &UploadResponse{}
-	if err != nil || file == nil {
+	if err != nil {
 		responseHandler(resp, err, c)
 		return
 	}
+	destination, ok := inst.resolvePath(c, destination, sandbox.Write)
+	if !ok {
+		return
+	}
 	if found := fileutils.DirExists(destination); !found {
 		responseHandler(nil, errors.New(fmt.Sprintf("destination not found %s", destination)), c)
 		return
 	}
-	toFileLocation := path.Join(destination, filepath.Base(file.Filename))
-	if err := c.SaveUploadedFile(file, toFileLocation); err != nil {
-		responseHandler(resp, err, c)
-		return
+	fileName := filepath.Base(upload.LocalFile)
+	if upload.File != nil {
+		fileName = filepath.Base(upload.File.Filename)
 	}
-	if err := os.Chmod(toFileLocation, os.FileMode(inst.FileMode)); err != nil {
+	toFileLocation := path.Join(destination, fileName)
+	err = checksum.WriteVerified(toFileLocation, upload.Checksum, os.FileMode(inst.FileMode), func(tmpFile string) error {
+		if upload.File != nil {
+			return c.SaveUploadedFile(upload.File, tmpFile)
+		}
+		return linkOrCopy(upload.LocalFile, tmpFile)
+	})
+	if err != nil {
 		responseHandler(resp, err, c)
 		return
 	}
+	done()
 	size, err := fileutils.GetFileSize(toFileLocation)
 	if err != nil {
 		responseHandler(resp, err, c)
@@ -157,19 +292,33 @@ func (inst *Controller) UploadFile(c *gin.Context) {
 	}
 	resp = &UploadResponse{
 		Destination: toFileLocation,
-		File:        file.Filename,
+		File:        fileName,
 		Size:        size.String(),
 		UploadTime:  TimeTrack(now),
+		Checksum:    upload.Checksum,
 	}
 	responseHandler(resp, nil, c)
 }
 
+// linkOrCopy leaves the source in place, so a chunked upload can be retried when a later step fails
+func linkOrCopy(source, destination string) error {
+	_ = os.Remove(destination)
+	if err := os.Link(source, destination); err == nil {
+		return nil
+	}
+	return fileutils.CopyFile(source, destination) // the destination may be on another filesystem
+}
+
 func (inst *Controller) ReadFile(c *gin.Context) {
 	file := c.Query("file")
 	if file == "" {
 		responseHandler(nil, errors.New("file can not be empty"), c)
 		return
 	}
+	file, ok := inst.resolvePath(c, file, sandbox.Read)
+	if !ok {
+		return
+	}
 	found := fileutils.FileExists(file)
 	if !found {
 		responseHandler(nil, errors.New(fmt.Sprintf("file not found: %s", file)), c)
@@ -194,6 +343,10 @@ func (inst *Controller) WriteFile(c *gin.Context) {
 		responseHandler(nil, errors.New("file can not be empty"), c)
 		return
 	}
+	file, ok := inst.resolvePath(c, file, sandbox.Write)
+	if !ok {
+		return
+	}
 	var m *WriteFile
 	err := c.ShouldBindJSON(&m)
 	if err != nil {
@@ -205,23 +358,29 @@ func (inst *Controller) WriteFile(c *gin.Context) {
 }
 
 func (inst *Controller) DeleteFile(c *gin.Context) {
-	file := c.Query("file")
+	file, ok := inst.resolveTree(c, c.Query("file"), sandbox.Remove)
+	if !ok {
+		return
+	}
 	if !fileutils.FileExists(file) {
 		responseHandler(nil, errors.New(fmt.Sprintf("file doesn't exist: %s", file)), c)
 		return
 	}
-	err := fileutils.Rm(file)
-	responseHandler(model.Message{Message: fmt.Sprintf("deleted file: %s", file)}, err, c)
+	message, err := inst.removePath(c, file)
+	responseHandler(model.Message{Message: message}, err, c)
 }
 
 func (inst *Controller) DeleteAllFiles(c *gin.Context) {
-	filePath := c.Query("path")
+	filePath, ok := inst.resolveTree(c, c.Query("path"), sandbox.Remove)
+	if !ok {
+		return
+	}
 	if !fileutils.FileOrDirExists(filePath) {
 		responseHandler(nil, errors.New(fmt.Sprintf("doesn't exist: %s", filePath)), c)
 		return
 	}
-	err := fileutils.RemoveAllFiles(filePath)
-	responseHandler(model.Message{Message: fmt.Sprintf("deleted path: %s", filePath)}, err, c)
+	message, err := inst.removePath(c, filePath)
+	responseHandler(model.Message{Message: message}, err, c)
 }
 
 func TimeTrack(start time.Time) (out string) {
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/logger"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/NubeIO/platform/services/search"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func searchOptions(c *gin.Context) (*search.Options, error) {
+	o := &search.Options{
+		Pattern:    c.Query("pattern"),
+		Regex:      c.Query("regex") == "true",
+		IgnoreCase: c.Query("ignoreCase") == "true",
+		Glob:       c.Query("glob"),
+	}
+	numbers := map[string]*int{"maxResults": &o.MaxResults, "context": &o.Context, "maxDepth": &o.MaxDepth}
+	for key, value := range numbers {
+		if raw := c.Query(key); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("%s must be a number", key))
+			}
+			*value = n
+		}
+	}
+	if raw := c.Query("maxFileSize"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("maxFileSize must be a number of bytes")
+		}
+		o.MaxFileSize = n
+	}
+	return o, nil
+}
+
+// SearchFiles streams the matching lines as newline delimited json, the last line is the summary
+// curl "http://localhost:1661/api/files/search?path=/data&pattern=192.168.15.10&glob=*.yml&context=2&maxResults=100"
+func (inst *Controller) SearchFiles(c *gin.Context) {
+	root, ok := inst.resolvePath(c, c.Query("path"), sandbox.Read)
+	if !ok {
+		return
+	}
+	o, err := searchOptions(c)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	// files under the denied paths, like the secrets, must not show up in the results
+	o.Skip = inst.Sandbox.Hidden
+	ctx := c.Request.Context()
+	encoder := json.NewEncoder(c.Writer)
+	started := false
+	emit := func(m *search.Match) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !started {
+			started = true
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+		}
+		if err := encoder.Encode(m); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	summary, err := search.Search(root, o, emit)
+	if !started {
+		if err != nil {
+			responseHandler(nil, err, c)
+			return
+		}
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+	if err != nil {
+		// the status is already sent, the error ends the stream instead of the summary
+		if ctx.Err() == nil {
+			logger.Logger.Errorf("search of %s failed: %s", root, err.Error())
+			_ = encoder.Encode(gin.H{"error": err.Error()})
+		}
+		return
+	}
+	_ = encoder.Encode(summary)
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/NubeIO/platform/utils/tail"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultFileTail = 100
+	maxFileTail     = 10000
+	followInterval  = 500 * time.Millisecond
+)
+
+type FileTail struct {
+	File  string   `json:"file"`
+	Size  int64    `json:"size"` // the offset to follow the file from
+	Lines []string `json:"lines"`
+}
+
+func (inst *Controller) resolveFile(c *gin.Context) (string, os.FileInfo, bool) {
+	file := c.Query("file")
+	if file == "" {
+		responseHandler(nil, errors.New("file can not be empty"), c)
+		return "", nil, false
+	}
+	file, ok := inst.resolvePath(c, file, sandbox.Read)
+	if !ok {
+		return "", nil, false
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		responseHandler(nil, errors.New(fmt.Sprintf("file not found: %s", file)), c, http.StatusNotFound)
+		return "", nil, false
+	}
+	return file, info, true
+}
+
+func queryInt64(c *gin.Context, key string, fallback int64) (int64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("%s must be a number", key))
+	}
+	return n, nil
+}
+
+// TailFile returns the last ?lines= of the file
+// curl "http://localhost:1661/api/files/tail?file=/data/rubix-os/data/rubix-os.log&lines=200"
+func (inst *Controller) TailFile(c *gin.Context) {
+	file, info, ok := inst.resolveFile(c)
+	if !ok {
+		return
+	}
+	n, err := queryInt64(c, "lines", defaultFileTail)
+	if err == nil && (n < 0 || n > maxFileTail) {
+		err = errors.New(fmt.Sprintf("lines needs to be between 0 and %d", maxFileTail))
+	}
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	lines, err := tail.Lines(file, int(n))
+	responseHandler(FileTail{File: file, Size: info.Size(), Lines: lines}, err, c)
+}
+
+// FollowFile streams the appended bytes as server-sent events, the event id is the offset to resume from with ?offset= or Last-Event-ID.
+// A ready event is sent right away, so that clients get the response on a quiet file as well
+// curl -N "http://localhost:1661/api/files/follow?file=/data/rubix-os/data/rubix-os.log"
+func (inst *Controller) FollowFile(c *gin.Context) {
+	file, _, ok := inst.resolveFile(c)
+	if !ok {
+		return
+	}
+	offset, err := queryInt64(c, "offset", -1)
+	if lastEventID := c.GetHeader("Last-Event-ID"); err == nil && c.Query("offset") == "" && lastEventID != "" {
+		offset, err = strconv.ParseInt(lastEventID, 10, 64)
+	}
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	err = tail.Follow(c.Request.Context(), file, offset, followInterval, func(e *tail.Event) error {
+		event := sse.Event{Id: strconv.FormatInt(e.Offset, 10), Event: "data", Data: string(e.Data)}
+		if e.Ready {
+			event.Event, event.Data = "ready", file
+		} else if e.Rotated != "" {
+			event.Event, event.Data = "rotated", e.Rotated
+		}
+		c.Render(-1, event)
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	})
+	if err == nil || c.Request.Context().Err() != nil {
+		return
+	}
+	if c.Writer.Written() {
+		c.Render(-1, sse.Event{Event: "error", Data: err.Error()})
+		c.Writer.Flush()
+	} else {
+		responseHandler(nil, err, c)
+	}
+}
+
+// ReadFileRange returns ?length= bytes from ?offset=, a negative offset counts back from the end of the file
+// curl "http://localhost:1661/api/files/range?file=/data/rubix-os/data/rubix-os.log&offset=-1048576"
+func (inst *Controller) ReadFileRange(c *gin.Context) {
+	file, _, ok := inst.resolveFile(c)
+	if !ok {
+		return
+	}
+	offset, err := queryInt64(c, "offset", 0)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	length, err := queryInt64(c, "length", 0)
+	if err == nil && length < 0 {
+		err = errors.New("length can not be negative")
+	}
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	reader, start, length, err := tail.Range(file, offset, length)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	defer reader.Close()
+	c.Header("X-Range-Offset", strconv.FormatInt(start, 10))
+	c.DataFromReader(http.StatusOK, length, "application/octet-stream", reader, nil)
+}
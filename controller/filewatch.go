@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"github.com/NubeIO/platform/services/fswatch"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"io"
+	"strings"
+)
+
+// WatchFiles sends the create, write, remove & rename events under the path, over a websocket when the request is an upgrade and as server-sent events otherwise
+// curl -N "http://localhost:1661/api/files/watch?path=/data/rubix-os/data&recursive=true"
+func (inst *Controller) WatchFiles(c *gin.Context) {
+	root, ok := inst.resolvePath(c, c.Query("path"), sandbox.Read)
+	if !ok {
+		return
+	}
+	o := &fswatch.Options{
+		Recursive: c.Query("recursive") == "true",
+		Skip:      inst.Sandbox.Hidden,
+	}
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		websocket.Handler(func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+			go func() {
+				// the client doesn't send anything, a failed read means it went away
+				_, _ = io.Copy(io.Discard, ws)
+				cancel()
+			}()
+			o.Ready = func() { _ = websocket.JSON.Send(ws, gin.H{"ready": root}) }
+			err := fswatch.Watch(ctx, root, o, func(e *fswatch.Event) error {
+				return websocket.JSON.Send(ws, e)
+			})
+			if err != nil && ctx.Err() == nil {
+				_ = websocket.JSON.Send(ws, gin.H{"error": err.Error()})
+			}
+		}).ServeHTTP(c.Writer, c.Request)
+		return
+	}
+	ctx := c.Request.Context()
+	ready := false
+	o.Ready = func() {
+		ready = true
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.SSEvent("ready", root)
+		c.Writer.Flush()
+	}
+	err := fswatch.Watch(ctx, root, o, func(e *fswatch.Event) error {
+		c.SSEvent(e.Op, e)
+		c.Writer.Flush()
+		return nil
+	})
+	switch {
+	case err == nil || ctx.Err() != nil:
+	case !ready:
+		responseHandler(nil, err, c)
+	default:
+		c.SSEvent("error", err.Error())
+	}
+}
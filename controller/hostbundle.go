@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/logger"
+	"github.com/NubeIO/platform/services/artifact"
+	"github.com/NubeIO/platform/services/hostbundle"
+	"github.com/NubeIO/platform/utils/checksum"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const BundleVersion = 1
+
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+const (
+	ImportCreated     = "created"
+	ImportOverwritten = "overwritten"
+	ImportRenamed     = "renamed"
+	ImportSkipped     = "skipped"
+	ImportFailed      = "failed"
+)
+
+// HostBundle is the portable set of hosts, secrets are encrypted with the key of the device so they are left out
+type HostBundle struct {
+	Version    int         `json:"version" yaml:"version"`
+	ExportedAt time.Time   `json:"exportedAt" yaml:"exported_at"`
+	Arch       string      `json:"arch" yaml:"arch"`
+	Hosts      []*Instance `json:"hosts" yaml:"hosts"`
+}
+
+type HostImport struct {
+	Name       string `json:"name"`
+	ImportedAs string `json:"importedAs,omitempty"`
+	Action     string `json:"action"` // created, overwritten, renamed, skipped or failed
+	Error      string `json:"error,omitempty"`
+}
+
+// ExportHosts bundles the named hosts, all of them when names is empty
+func (inst *Controller) ExportHosts(names []string) (*HostBundle, error) {
+	inst.Lock.Lock()
+	defer inst.Lock.Unlock()
+	if len(names) == 0 {
+		for name := range inst.Instances {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	bundle := &HostBundle{
+		Version:    BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Arch:       inst.Config.GetArch(),
+		Hosts:      make([]*Instance, 0, len(names)),
+	}
+	for _, name := range names {
+		instance, err := inst.GetInstance(name)
+		if err != nil {
+			return nil, err
+		}
+		exported := *instance
+		exported.Secrets = nil
+		bundle.Hosts = append(bundle.Hosts, &exported)
+	}
+	return bundle, nil
+}
+
+// ExportHostsHandler returns the bundle as yaml or json, or as a zip with the artifacts when ?artifacts=true
+func (inst *Controller) ExportHostsHandler(c *gin.Context) {
+	var names []string
+	if value := c.Query("names"); value != "" {
+		names = strings.Split(value, ",")
+	}
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be yaml or json"})
+		return
+	}
+	bundle, err := inst.ExportHosts(names)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	withArtifacts := c.Query("artifacts") == "true"
+	// fetch everything first, the status can't change once the zip is being streamed
+	artifacts := make([]*bundleArtifact, 0)
+	for _, host := range bundle.Hosts {
+		if !withArtifacts || host.Repo == "" || host.Version == "" {
+			continue
+		}
+		bundled, err := inst.prepareBundleArtifact(host)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("artifact of host %s: %s", host.Name, err.Error())})
+			return
+		}
+		artifacts = append(artifacts, bundled)
+	}
+	data, err := encodeBundle(bundle, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !withArtifacts {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=hosts.%s", format))
+		c.Data(http.StatusOK, fmt.Sprintf("application/%s", format), data)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=hosts.zip")
+	c.Status(http.StatusOK)
+	w := hostbundle.NewWriter(c.Writer)
+	if err = w.Add(fmt.Sprintf("hosts.%s", format), bytes.NewReader(data)); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	for _, bundled := range artifacts {
+		if err = w.AddFile(bundled.entry, bundled.filePath); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+	if err = w.Close(); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+type bundleArtifact struct {
+	entry    string
+	filePath string
+}
+
+// prepareBundleArtifact fetches the artifact and pins its checksum on the exported host, the import verifies the artifact
+// against it; that catches corruption, bundles have to come from a trusted source as the checksum travels with them
+func (inst *Controller) prepareBundleArtifact(host *Instance) (*bundleArtifact, error) {
+	if err := inst.ensureInstanceArtifact(host); err != nil {
+		return nil, err
+	}
+	spec := inst.instanceArtifact(host)
+	entry, err := hostbundle.ArtifactPath(spec.Name, spec.Version, spec.FileName())
+	if err != nil {
+		return nil, err
+	}
+	downloadPath := inst.Artifacts.DownloadPath(spec)
+	if host.Checksum == "" {
+		digest, err := checksum.File(downloadPath, checksum.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		host.Checksum = fmt.Sprintf("%s:%s", checksum.SHA256, digest)
+	}
+	return &bundleArtifact{entry: entry, filePath: downloadPath}, nil
+}
+
+func encodeBundle(bundle *HostBundle, format string) ([]byte, error) {
+	if format == "json" {
+		return json.MarshalIndent(bundle, "", "  ")
+	}
+	return yaml.Marshal(bundle)
+}
+
+// decodeBundle parses the json or yaml hosts document
+func decodeBundle(data []byte) (*HostBundle, error) {
+	bundle := &HostBundle{}
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(data, bundle)
+	} else {
+		err = yaml.Unmarshal(data, bundle)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid bundle: %s", err.Error()))
+	}
+	if bundle.Version > BundleVersion {
+		return nil, errors.New(fmt.Sprintf("bundle version %d is newer than the supported version %d", bundle.Version, BundleVersion))
+	}
+	return bundle, nil
+}
+
+// ImportHosts applies the bundle host by host, a failing host doesn't stop the others
+func (inst *Controller) ImportHosts(bundle *HostBundle, archive *hostbundle.Reader, conflict string, force bool) []*HostImport {
+	results := make([]*HostImport, 0, len(bundle.Hosts))
+	for _, host := range bundle.Hosts {
+		result := &HostImport{Name: host.Name}
+		if err := inst.importHost(host, archive, conflict, force, result); err != nil {
+			result.Action = ImportFailed
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (inst *Controller) importHost(host *Instance, archive *hostbundle.Reader, conflict string, force bool, result *HostImport) error {
+	original := host.Name
+	// the name & version pick the paths of the bundled artifact, so they are checked before anything is extracted
+	if err := validateInstance(host); err != nil {
+		return err
+	}
+	inst.Lock.Lock()
+	existing, exists := inst.Instances[original]
+	var err error
+	if exists && conflict == ConflictRename {
+		// the copy would always fail the port check against the host it's a copy of
+		if host.Port != 0 && host.Port == existing.Port && instanceProtocol(host) == instanceProtocol(existing) {
+			err = errors.New(fmt.Sprintf("port %d/%s is in use by %s, change the port of the host in the bundle or import it with conflict=%s",
+				host.Port, instanceProtocol(host), original, ConflictOverwrite))
+		} else {
+			host.Name = inst.freeInstanceName(original)
+		}
+	}
+	inst.Lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if exists && conflict == ConflictSkip {
+		result.Action = ImportSkipped
+		return nil
+	}
+	if host.Name != original && host.Repo != "" {
+		// the artifact is still the one of the original name
+		artifactName := host.Artifact
+		if artifactName == "" {
+			artifactName = artifact.DefaultArtifact
+		}
+		host.Artifact = strings.ReplaceAll(artifactName, "{name}", original)
+	}
+	if err := inst.extractBundleArtifact(archive, original, host); err != nil {
+		return err
+	}
+	result.ImportedAs = host.Name
+	if exists && conflict == ConflictOverwrite {
+		if len(host.Secrets) == 0 {
+			host.Secrets = existing.Secrets
+		}
+		if _, err := inst.UpdateInstance(original, host, nil, force); err != nil {
+			return err
+		}
+		result.Action = ImportOverwritten
+		return nil
+	}
+	if err := inst.AddInstance(host, force); err != nil {
+		return err
+	}
+	result.Action = ImportCreated
+	if host.Name != original {
+		result.Action = ImportRenamed
+	}
+	return nil
+}
+
+// freeInstanceName appends -2, -3... until the name is free, it needs inst.Lock to be held
+func (inst *Controller) freeInstanceName(name string) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if _, found := inst.Instances[candidate]; !found {
+			return candidate
+		}
+	}
+}
+
+// extractBundleArtifact puts the bundled artifact into the download cache, where Ensure picks it up
+func (inst *Controller) extractBundleArtifact(archive *hostbundle.Reader, original string, host *Instance) error {
+	if archive == nil || host.Repo == "" || host.Version == "" {
+		return nil
+	}
+	spec := inst.instanceArtifact(host)
+	entry, err := hostbundle.ArtifactPath(original, host.Version, spec.FileName())
+	if err != nil {
+		return err
+	}
+	if host.Checksum == "" {
+		logger.Logger.Warnf("host %s has no checksum, its bundled artifact is ignored and it gets fetched from the repo", host.Name)
+		return nil
+	}
+	downloadPath := inst.Artifacts.DownloadPath(spec)
+	if err = os.MkdirAll(path.Dir(downloadPath), os.FileMode(inst.FileMode)); err != nil {
+		return err
+	}
+	_, err = archive.ExtractArtifact(entry, downloadPath, host.Checksum)
+	return err
+}
+
+// ImportHostsHandler takes the bundle as the body or as the `file` of a multipart form, ?conflict=skip|overwrite|rename
+func (inst *Controller) ImportHostsHandler(c *gin.Context) {
+	conflict := c.DefaultQuery("conflict", ConflictSkip)
+	if conflict != ConflictSkip && conflict != ConflictOverwrite && conflict != ConflictRename {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "conflict must be skip, overwrite or rename"})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, inst.MaxImportBytes)
+	bundleFile, size, cleanup, err := inst.receiveBundle(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("the bundle is bigger than %d MB", inst.MaxImportBytes/1024/1024)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer cleanup()
+	document, archive, err := hostbundle.Open(bundleFile, size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bundle, err := decodeBundle(document)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results := inst.ImportHosts(bundle, archive, conflict, isForced(c))
+	if err = inst.SaveToFile(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"hosts": results})
+}
+
+// receiveBundle returns the `file` of a multipart form or the body spooled into a temp file, the zip needs random access
+func (inst *Controller) receiveBundle(c *gin.Context) (io.ReaderAt, int64, func(), error) {
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		return f, file.Size, func() { _ = f.Close() }, nil
+	}
+	if err := inst.Store.Installer.MakeTmpDir(); err != nil {
+		return nil, 0, nil, err
+	}
+	tmp, err := os.CreateTemp(inst.Store.Installer.TmpDir, "hosts-import-*")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup := func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}
+	size, err := io.Copy(tmp, c.Request.Body)
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return tmp, size, cleanup, nil
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportRenamedHostWithPort(t *testing.T) {
+	inst := &Controller{Instances: map[string]*Instance{
+		"rubix-os": {Name: "rubix-os", Port: 1660},
+	}}
+	bundle := &HostBundle{Hosts: []*Instance{{Name: "rubix-os", Port: 1660}}}
+	results := inst.ImportHosts(bundle, nil, ConflictRename, false)
+	if len(results) != 1 || results[0].Action != ImportFailed || !strings.Contains(results[0].Error, "port 1660/tcp is in use by rubix-os") {
+		t.Fatalf("expected the renamed copy to fail on the port of the original, got %+v", results[0])
+	}
+	if results[0].ImportedAs != "" || len(inst.Instances) != 1 {
+		t.Fatalf("expected nothing to be imported, got %+v", inst.Instances)
+	}
+
+	bundle = &HostBundle{Hosts: []*Instance{{Name: "rubix-os", Port: 1660}}}
+	results = inst.ImportHosts(bundle, nil, ConflictSkip, false)
+	if results[0].Action != ImportSkipped {
+		t.Fatalf("expected the host to be skipped, got %+v", results[0])
+	}
+}
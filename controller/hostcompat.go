@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInstanceCompatibility rejects instances which are not built for this device, empty lists allow everything
+func (inst *Controller) checkInstanceCompatibility(instance *Instance) error {
+	if len(instance.Arch) > 0 {
+		arch := inst.Config.GetArch()
+		if !containsFold(instance.Arch, arch) {
+			return errors.New(fmt.Sprintf("instance %s supports arch %s but this device is %s, use force=true to override",
+				instance.Name, strings.Join(instance.Arch, ", "), arch))
+		}
+	}
+	if len(instance.Products) > 0 {
+		productInfo, err := inst.Registry.GetProductInfo()
+		if err != nil {
+			return err
+		}
+		if productInfo.Type == "" {
+			return errors.New(fmt.Sprintf("instance %s supports products %s but the product type of this device is unknown, use force=true to override",
+				instance.Name, strings.Join(instance.Products, ", ")))
+		}
+		if !containsFold(instance.Products, productInfo.Type) {
+			return errors.New(fmt.Sprintf("instance %s supports products %s but this device is %s, use force=true to override",
+				instance.Name, strings.Join(instance.Products, ", "), productInfo.Type))
+		}
+	}
+	return nil
+}
+
+func (inst *Controller) CheckInstanceCompatibility(name string, force bool) error {
+	instance, err := inst.GetInstance(name)
+	if err != nil {
+		return err
+	}
+	if force {
+		return nil
+	}
+	return inst.checkInstanceCompatibility(instance)
+}
+
+func isForced(c *gin.Context) bool {
+	return c.Query("force") == "true"
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/NubeIO/platform/logger"
+	"github.com/NubeIO/platform/services/hostevents"
+	"github.com/NubeIO/platform/services/limits"
+	"github.com/NubeIO/platform/services/restart"
+	"github.com/NubeIO/platform/services/supervisor"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (inst *Controller) recordEvent(name string, event hostevents.Event) {
+	if err := inst.Events.Record(name, event); err != nil {
+		logger.Logger.Errorf("failed to record %s event of host %s: %s", event.Type, name, err.Error())
+	}
+}
+
+// instanceExited records the exit and applies the restart policy, the restart itself runs later so the exit never waits on inst.Lock
+func (inst *Controller) instanceExited(name string) func(status *supervisor.Status) {
+	return func(status *supervisor.Status) {
+		if err := limits.Release(name); err != nil {
+			logger.Logger.Warnf("failed to remove the cgroup of host %s: %s", name, err.Error())
+		}
+		if status.State == supervisor.StateStopped {
+			inst.recordEvent(name, hostevents.Event{Type: hostevents.TypeStop, ExitCode: status.ExitCode})
+			return
+		}
+		inst.recordEvent(name, hostevents.Event{Type: hostevents.TypeExit, ExitCode: status.ExitCode, Message: status.Error})
+		exitCode := 0
+		if status.ExitCode != nil {
+			exitCode = *status.ExitCode
+		}
+		logger.Logger.Warnf("host %s exited with code %d", name, exitCode)
+		go func() {
+			inst.Lock.Lock()
+			instance, err := inst.GetInstance(name)
+			inst.Lock.Unlock()
+			if err != nil || instance.Restart == nil || !instance.Restart.ShouldRestart(exitCode) {
+				return
+			}
+			inst.scheduleRestart(name, instance.Restart, fmt.Sprintf("exit code %d", exitCode))
+		}()
+	}
+}
+
+func (inst *Controller) scheduleRestart(name string, policy *restart.Policy, reason string) {
+	delay, err := inst.Restarts.Schedule(name, policy, func() { inst.restartCrashedInstance(name) })
+	if err != nil {
+		logger.Logger.Errorf("not restarting host %s anymore: %s", name, err.Error())
+		inst.recordEvent(name, hostevents.Event{Type: hostevents.TypeGaveUp, Message: err.Error()})
+		return
+	}
+	logger.Logger.Infof("restarting host %s in %s after %s", name, delay, reason)
+	inst.recordEvent(name, hostevents.Event{Type: hostevents.TypeRestart, Message: fmt.Sprintf("restarting in %s after %s", delay, reason)})
+}
+
+func (inst *Controller) restartCrashedInstance(name string) {
+	inst.Lock.Lock()
+	instance, ok := inst.crashedInstance(name)
+	inst.Lock.Unlock()
+	if !ok {
+		return
+	}
+	// the download can take minutes, it happens before taking the lock which every host call needs
+	if err := inst.ensureInstanceArtifact(instance); err != nil {
+		inst.recordEvent(name, hostevents.Event{Type: hostevents.TypeStartFailed, Message: err.Error()})
+		if instance.Restart != nil {
+			inst.scheduleRestart(name, instance.Restart, err.Error())
+		}
+		return
+	}
+	inst.Lock.Lock()
+	defer inst.Lock.Unlock()
+	// checked again, the host could have been changed while the artifact got fetched
+	if instance, ok = inst.crashedInstance(name); !ok {
+		return
+	}
+	if err := inst.startInstance(instance); err != nil && instance.Restart != nil {
+		inst.scheduleRestart(name, instance.Restart, err.Error())
+	}
+}
+
+// crashedInstance returns the instance while it still needs the restart, it needs inst.Lock to be held
+func (inst *Controller) crashedInstance(name string) (*Instance, bool) {
+	instance, err := inst.GetInstance(name)
+	if err != nil || instance.IsSystemd() || inst.Supervisor.IsRunning(name) {
+		return nil, false // deleted, moved to systemd or started by hand in the meantime
+	}
+	return instance, true
+}
+
+func (inst *Controller) GetInstanceEventsHandler(c *gin.Context) {
+	name := c.Param("name")
+	inst.Lock.Lock()
+	_, err := inst.GetInstance(name)
+	inst.Lock.Unlock()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	events, err := inst.Events.List(name, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/NubeIO/platform/dto"
+	"github.com/NubeIO/platform/services/probe"
+	"github.com/NubeIO/platform/services/supervisor"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type InstanceHealth struct {
+	Name      string        `json:"name"`
+	Status    string        `json:"status"` // green, orange or red
+	State     string        `json:"state"`
+	Liveness  *probe.Result `json:"liveness,omitempty"`
+	Readiness *probe.Result `json:"readiness,omitempty"`
+}
+
+func livenessKey(name string) string {
+	return fmt.Sprintf("%s/liveness", name)
+}
+
+func readinessKey(name string) string {
+	return fmt.Sprintf("%s/readiness", name)
+}
+
+func (inst *Controller) watchInstance(instance *Instance) {
+	inst.unwatchInstance(instance.Name)
+	if instance.Liveness != nil {
+		inst.Probes.Watch(livenessKey(instance.Name), instanceProbe(instance, instance.Liveness), instance.Port)
+	}
+	if instance.Readiness != nil {
+		inst.Probes.Watch(readinessKey(instance.Name), instanceProbe(instance, instance.Readiness), instance.Port)
+	}
+}
+
+func (inst *Controller) unwatchInstance(name string) {
+	inst.Probes.Unwatch(livenessKey(name))
+	inst.Probes.Unwatch(readinessKey(name))
+}
+
+func instanceProbe(instance *Instance, config *probe.Config) probe.Config {
+	p := *config
+	if p.Type == "" {
+		p.Type = probe.TypeFromTransport(instance.Transport)
+	}
+	return p
+}
+
+// GetInstanceHealth is red when the process is down or liveness fails, orange when it's not ready yet
+func (inst *Controller) GetInstanceHealth(name string) (*InstanceHealth, error) {
+	status, err := inst.GetInstanceStatus(name)
+	if err != nil {
+		return nil, err
+	}
+	health := &InstanceHealth{
+		Name:      name,
+		State:     status.State,
+		Liveness:  inst.Probes.Result(livenessKey(name)),
+		Readiness: inst.Probes.Result(readinessKey(name)),
+	}
+	switch {
+	case status.State != supervisor.StateRunning:
+		health.Status = dto.StatusRed
+	case health.Liveness != nil && health.Liveness.Failing():
+		health.Status = dto.StatusRed
+	case health.Liveness != nil && health.Liveness.ConsecutiveFailures > 0:
+		health.Status = dto.StatusOrange
+	case health.Readiness != nil && (!health.Readiness.Checked || health.Readiness.Failing()):
+		health.Status = dto.StatusOrange
+	default:
+		health.Status = dto.StatusGreen
+	}
+	return health, nil
+}
+
+func (inst *Controller) GetInstanceHealthHandler(c *gin.Context) {
+	health, err := inst.GetInstanceHealth(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
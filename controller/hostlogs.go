@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const defaultLogTail = 100
+
+func (inst *Controller) GetInstanceLogs(name string, n int) ([]string, error) {
+	instance, err := inst.GetInstance(name)
+	if err != nil {
+		return nil, err
+	}
+	if instance.IsSystemd() {
+		return journalTail(instanceServiceName(instance), n)
+	}
+	return inst.Logs.Get(name).Tail(n)
+}
+
+// GetInstanceLogsHandler
+// curl http://localhost:1772/api/hosts/my-host/logs?tail=200
+func (inst *Controller) GetInstanceLogsHandler(c *gin.Context) {
+	n := defaultLogTail
+	if t := c.Query("tail"); t != "" {
+		var err error
+		n, err = strconv.Atoi(t)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tail must be a positive number"})
+			return
+		}
+	}
+	lines, err := inst.GetInstanceLogs(c.Param("name"), n)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lines": lines})
+}
+
+// StreamInstanceLogsHandler follows the output as server-sent events
+// curl -N http://localhost:1772/api/hosts/my-host/logs/stream
+func (inst *Controller) StreamInstanceLogsHandler(c *gin.Context) {
+	instance, err := inst.GetInstance(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if instance.IsSystemd() {
+		streamJournal(c, instanceServiceName(instance))
+		return
+	}
+	ch, unsubscribe := inst.Logs.Get(instance.Name).Subscribe()
+	defer unsubscribe()
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case data := <-ch:
+			c.SSEvent("log", string(data))
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+func journalTail(unit string, n int) ([]string, error) {
+	out, err := exec.Command("journalctl", "-u", unit, "-n", strconv.Itoa(n), "--no-pager", "-o", "cat").Output()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("journalctl: %s", err.Error()))
+	}
+	text := strings.TrimSuffix(string(out), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+func streamJournal(c *gin.Context, unit string) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "journalctl", "-u", unit, "-f", "-n", "0", "--no-pager", "-o", "cat")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err = cmd.Start(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text() + "\n":
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return false
+			}
+			c.SSEvent("log", line)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/services/ports"
+	"github.com/NubeIO/platform/services/probe"
+)
+
+func instanceProtocol(instance *Instance) string {
+	if probe.TypeFromTransport(instance.Transport) == probe.TypeUDP {
+		return ports.UDP
+	}
+	return ports.TCP
+}
+
+// checkPortConflicts makes sure no other instance and no process on the host is using the port, it needs inst.Lock to be held
+func (inst *Controller) checkPortConflicts(instance *Instance) error {
+	if instance.Port == 0 {
+		return nil
+	}
+	protocol := instanceProtocol(instance)
+	for _, other := range inst.Instances {
+		if other.Name == instance.Name || other.Port != instance.Port || instanceProtocol(other) != protocol {
+			continue
+		}
+		return errors.New(fmt.Sprintf("port %d/%s is already used by instance %s", instance.Port, protocol, other.Name))
+	}
+	listener, err := ports.Find(protocol, instance.Port)
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to check port %d/%s: %s", instance.Port, protocol, err.Error()))
+	}
+	if listener != nil {
+		return errors.New(fmt.Sprintf("port %d/%s is already in use by %s", instance.Port, protocol, listener.Owner()))
+	}
+	return nil
+}
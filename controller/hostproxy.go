@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/services/ports"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// InstanceProxy forwards /hosts/:name/proxy/*path to the port of the instance
+func (inst *Controller) InstanceProxy(c *gin.Context) {
+	name := c.Param("name")
+	inst.Lock.Lock()
+	instance, err := inst.GetInstance(name)
+	var port int
+	var protocol string
+	if err == nil {
+		port, protocol = instance.Port, instanceProtocol(instance)
+	}
+	inst.Lock.Unlock()
+	if err != nil {
+		responseHandler(nil, err, c, http.StatusNotFound)
+		return
+	}
+	if port == 0 {
+		responseHandler(nil, errors.New(fmt.Sprintf("instance %s has no port to proxy to", name)), c)
+		return
+	}
+	if protocol != ports.TCP {
+		responseHandler(nil, errors.New(fmt.Sprintf("instance %s uses %s, only tcp can be proxied", name, protocol)), c)
+		return
+	}
+	proxyTo(c, "127.0.0.1", port, c.Param("path"), func(req *http.Request) {
+		// the credentials of the platform are none of the instance's business
+		req.Header = req.Header.Clone()
+		req.Header.Del("Authorization")
+		req.Header.Del("Cookie")
+		req.Header.Set("X-Forwarded-Prefix", fmt.Sprintf("/hosts/%s/proxy", name))
+		req.URL.RawPath = ""
+	})
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/logger"
+	"github.com/NubeIO/platform/services/secrets"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sealSecrets encrypts the plain values, a redacted or unchanged value keeps the stored cipher text so it doesn't show up as a change
+func (inst *Controller) sealSecrets(instance, existing *Instance) error {
+	for key, value := range instance.Secrets {
+		if !envName.MatchString(key) {
+			return errors.New(fmt.Sprintf("invalid secret name %s", key))
+		}
+		var stored string
+		if existing != nil {
+			stored = existing.Secrets[key]
+		}
+		switch {
+		case value == secrets.Redacted:
+			if stored == "" {
+				return errors.New(fmt.Sprintf("secret %s has no stored value", key))
+			}
+			instance.Secrets[key] = stored
+		case secrets.IsEncrypted(value):
+			// only cipher text of this device's key is accepted, anything else would only fail on start
+			if _, err := inst.Secrets.Decrypt(value); err != nil {
+				return errors.New(fmt.Sprintf("secret %s: %s", key, err.Error()))
+			}
+		default:
+			if stored != "" {
+				if plain, err := inst.Secrets.Decrypt(stored); err == nil && plain == value {
+					instance.Secrets[key] = stored
+					continue
+				}
+			}
+			encrypted, err := inst.Secrets.Encrypt(value)
+			if err != nil {
+				return err
+			}
+			instance.Secrets[key] = encrypted
+		}
+	}
+	return nil
+}
+
+// secretEnv decrypts the secrets as KEY=value, sorted so that the unit file is stable
+func (inst *Controller) secretEnv(instance *Instance) ([]string, error) {
+	env := make([]string, 0, len(instance.Secrets))
+	for key, value := range instance.Secrets {
+		plain, err := inst.Secrets.Decrypt(value)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("secret %s: %s", key, err.Error()))
+		}
+		env = append(env, fmt.Sprintf("%s=%s", key, plain))
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// sealedEnv is the env of the systemd unit, the values stay encrypted until the exec command decrypts them in memory
+func (inst *Controller) sealedEnv(instance *Instance) []string {
+	env := make([]string, 0, len(instance.Secrets))
+	for key, value := range instance.Secrets {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(env)
+	return env
+}
+
+// decryptingCommand starts the command through the exec command of the platform, which decrypts the sealed env
+func (inst *Controller) decryptingCommand(args []string) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{executable, "exec", "--key", inst.Secrets.KeyPath, "--"}, args...), nil
+}
+
+// legacySecretEnvFile is where older versions wrote the decrypted secrets for systemd
+func (inst *Controller) legacySecretEnvFile(instance *Instance) string {
+	return path.Join(inst.Config.GetAbsDataDir(), "hosts", "secrets", fmt.Sprintf("%s.env", instance.Name))
+}
+
+// migrateSecretEnvFile reinstalls the units which still read the plain text env file, and removes the file
+func (inst *Controller) migrateSecretEnvFile(instance *Instance) {
+	envFile := inst.legacySecretEnvFile(instance)
+	if _, err := os.Stat(envFile); err != nil {
+		return
+	}
+	if inst.Units.Exists(instanceServiceName(instance)) {
+		if err := inst.installInstanceUnit(instance); err != nil {
+			logger.Logger.Errorf("failed to reinstall the unit of host %s: %s", instance.Name, err.Error())
+			return
+		}
+	}
+	if err := os.Remove(envFile); err != nil {
+		logger.Logger.Errorf("failed to remove %s: %s", envFile, err.Error())
+	}
+}
+
+// redacted is the copy of the instance which is safe to return from the API
+func (instance *Instance) redacted() *Instance {
+	if len(instance.Secrets) == 0 {
+		return instance
+	}
+	copied := *instance
+	copied.Secrets = redactSecrets(instance.Secrets)
+	return &copied
+}
+
+func redactSecrets(values map[string]string) map[string]string {
+	redacted := make(map[string]string, len(values))
+	for key := range values {
+		redacted[key] = secrets.Redacted
+	}
+	return redacted
+}
+
+func redactInstances(instances []*Instance) []*Instance {
+	redacted := make([]*Instance, 0, len(instances))
+	for _, instance := range instances {
+		redacted = append(redacted, instance.redacted())
+	}
+	return redacted
+}
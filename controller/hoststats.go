@@ -0,0 +1,77 @@
+package controller
+
+import (
+	systeminfo "github.com/NubeIO/platform/services/system"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type InstanceStats struct {
+	Name  string                       `json:"name"`
+	State string                       `json:"state"`
+	Stats *systeminfo.ProcessTreeStats `json:"stats,omitempty"` // nil when the instance isn't running
+}
+
+// GetInstancesStats samples the process trees of the instances together, so it takes one interval whatever the count
+func (inst *Controller) GetInstancesStats(names []string, interval time.Duration) ([]*InstanceStats, error) {
+	stats := make([]*InstanceStats, 0, len(names))
+	pids := make([]int32, 0, len(names))
+	for _, name := range names {
+		status, err := inst.GetInstanceStatus(name)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, &InstanceStats{Name: name, State: status.State})
+		pids = append(pids, int32(status.PID))
+	}
+	trees, err := inst.SystemInfo.GetProcessTreeStats(pids, interval)
+	if err != nil {
+		return nil, err
+	}
+	for i, tree := range trees {
+		stats[i].Stats = tree
+	}
+	return stats, nil
+}
+
+func statsInterval(c *gin.Context) (time.Duration, bool) {
+	ms, err := strconv.Atoi(c.DefaultQuery("interval_ms", "500"))
+	if err != nil || ms < 0 || ms > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval_ms must be between 0 and 10000"})
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+func (inst *Controller) GetInstanceStatsHandler(c *gin.Context) {
+	interval, ok := statsInterval(c)
+	if !ok {
+		return
+	}
+	stats, err := inst.GetInstancesStats([]string{c.Param("name")}, interval)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats[0])
+}
+
+func (inst *Controller) GetAllInstancesStatsHandler(c *gin.Context) {
+	interval, ok := statsInterval(c)
+	if !ok {
+		return
+	}
+	instances := inst.GetAllInstances()
+	names := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		names = append(names, instance.Name)
+	}
+	stats, err := inst.GetInstancesStats(names, interval)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
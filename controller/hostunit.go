@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"github.com/NubeIO/platform/constants"
+	"github.com/NubeIO/platform/services/supervisor"
+	"github.com/NubeIO/platform/services/unitfile"
+	"os"
+)
+
+func instanceServiceName(instance *Instance) string {
+	return constants.GetServiceNameFromAppName(instance.Name)
+}
+
+func (inst *Controller) instanceUnit(instance *Instance) (*unitfile.Unit, error) {
+	args, workingDir, err := inst.instanceCommand(instance)
+	if err != nil {
+		return nil, err
+	}
+	if len(instance.Secrets) > 0 {
+		if args, err = inst.decryptingCommand(args); err != nil {
+			return nil, err
+		}
+	}
+	description := instance.Description
+	if description == "" {
+		description = instance.Name
+	}
+	unit := &unitfile.Unit{
+		Description:      description,
+		WorkingDirectory: workingDir,
+		ExecStart:        args,
+		Environment:      append(append([]string{}, instance.EnvironmentVars...), inst.sealedEnv(instance)...),
+		SyslogIdentifier: instance.Name,
+	}
+	// systemd has no exponential backoff, it restarts after the initial delay until the start limit is hit
+	if policy := instance.Restart; policy != nil {
+		unit.Restart = policy.SystemdRestart()
+		unit.RestartSec = int(policy.InitialDelay().Seconds())
+		unit.StartLimitIntervalSec = int(policy.Window().Seconds())
+		unit.StartLimitBurst = policy.Crashes()
+	}
+	if l := instance.Limits; l != nil {
+		unit.MemoryMaxMB = l.MemoryMaxMB
+		unit.CPUQuotaPercent = l.CPUQuotaPercent
+		unit.Nice = l.Nice
+		unit.LimitNOFILE = l.MaxOpenFiles
+	}
+	return unit, nil
+}
+
+func (inst *Controller) installInstanceUnit(instance *Instance) error {
+	if err := inst.ensureInstanceArtifact(instance); err != nil {
+		return err
+	}
+	unit, err := inst.instanceUnit(instance)
+	if err != nil {
+		return err
+	}
+	return inst.Units.Install(instanceServiceName(instance), unit)
+}
+
+func (inst *Controller) uninstallInstanceUnit(instance *Instance) error {
+	if err := inst.Units.Uninstall(instanceServiceName(instance)); err != nil {
+		return err
+	}
+	if err := os.Remove(inst.legacySecretEnvFile(instance)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (inst *Controller) instanceUnitStatus(instance *Instance) *supervisor.Status {
+	serviceName := instanceServiceName(instance)
+	status := &supervisor.Status{Name: instance.Name, State: supervisor.StateStopped}
+	active, state, err := inst.SystemCtl.IsActive(serviceName)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if active {
+		status.State = supervisor.StateRunning
+		status.PID, _ = inst.SystemCtl.GetPID(serviceName)
+		if startedAt, err := inst.SystemCtl.GetStartTime(serviceName); err == nil {
+			status.StartedAt = &startedAt
+		}
+	} else if state == "failed" {
+		status.State = supervisor.StateExited
+	}
+	return status
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/logger"
+	"github.com/NubeIO/platform/services/fieldpatch"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type FieldChange = fieldpatch.Change
+
+type InstanceUpdate struct {
+	Instance  *Instance      `json:"instance"`
+	Changes   []*FieldChange `json:"changes"`
+	Restarted bool           `json:"restarted"`
+}
+
+// runtimeFields are the ones which need the instance to be restarted to take effect
+var runtimeFields = map[string]bool{
+	"execStart":                   true,
+	"attachWorkingDirOnExecStart": true,
+	"environmentVars":             true,
+	"port":                        true,
+	"mode":                        true,
+	"repo":                        true,
+	"version":                     true,
+	"artifact":                    true,
+	"checksum":                    true,
+	"secrets":                     true,
+	"limits":                      true,
+}
+
+func needsRestart(changes []*FieldChange) bool {
+	for _, change := range changes {
+		if runtimeFields[change.Field] {
+			return true
+		}
+	}
+	return false
+}
+
+func artifactChanged(old, new *Instance) bool {
+	return old.Repo != new.Repo || old.Version != new.Version || old.Artifact != new.Artifact || old.Checksum != new.Checksum
+}
+
+// ErrInstanceChanged is returned when the instance got updated since base was read
+var ErrInstanceChanged = errors.New("the host was changed in the meantime, read it again and retry")
+
+// UpdateInstance replaces the instance, base is the one a patch was merged against, the update is refused when the
+// stored one isn't base anymore; nil replaces whatever is stored
+func (inst *Controller) UpdateInstance(name string, updated, base *Instance, force bool) (*InstanceUpdate, error) {
+	if updated.Name == "" {
+		updated.Name = name
+	}
+	if updated.Name != name {
+		return nil, fmt.Errorf("instance name can not be changed from %s to %s", name, updated.Name)
+	}
+	if err := validateInstance(updated); err != nil {
+		return nil, err
+	}
+	// a new artifact is fetched before taking the lock, the setup below then finds it in the cache
+	inst.Lock.Lock()
+	existing, found := inst.Instances[name]
+	inst.Lock.Unlock()
+	if found && artifactChanged(existing, updated) {
+		if !force {
+			if err := inst.checkInstanceCompatibility(updated); err != nil {
+				return nil, err
+			}
+		}
+		if err := inst.ensureInstanceArtifact(updated); err != nil {
+			return nil, err
+		}
+	}
+
+	inst.Lock.Lock()
+	defer inst.Lock.Unlock()
+	existing, found = inst.Instances[name]
+	if !found {
+		return nil, fmt.Errorf("instance with name %s not found", name)
+	}
+	if base != nil && existing != base {
+		return nil, ErrInstanceChanged
+	}
+	if err := inst.sealSecrets(updated, existing); err != nil {
+		return nil, err
+	}
+	changes := fieldpatch.Diff(existing, updated)
+	result := &InstanceUpdate{Instance: updated, Changes: changes}
+	if len(changes) == 0 {
+		return result, nil
+	}
+	// the restart policy of supervised processes is read on exit, only units need to be rewritten
+	restartNeeded := needsRestart(changes) || (updated.IsSystemd() && fieldpatch.Has(changes, "restart"))
+	if !force && (restartNeeded || fieldpatch.Has(changes, "arch", "products")) {
+		if err := inst.checkInstanceCompatibility(updated); err != nil {
+			return nil, err
+		}
+	}
+	if existing.Port != updated.Port || instanceProtocol(existing) != instanceProtocol(updated) {
+		if err := inst.checkPortConflicts(updated); err != nil {
+			return nil, err
+		}
+	}
+	if !restartNeeded {
+		inst.Instances[name] = updated
+		inst.watchInstance(updated)
+		return result, nil
+	}
+
+	wasRunning := existing.IsSystemd() || inst.Supervisor.IsRunning(name) || inst.Restarts.Pending(name)
+	if err := inst.teardownInstance(existing); err != nil {
+		return nil, err
+	}
+	inst.Instances[name] = updated
+	if err := inst.setupInstance(updated, wasRunning); err != nil {
+		logger.Logger.Errorf("failed to apply update on host %s, rolling back: %s", name, err.Error())
+		inst.Instances[name] = existing
+		if rollbackErr := inst.setupInstance(existing, wasRunning); rollbackErr != nil {
+			logger.Logger.Errorf("failed to roll back host %s: %s", name, rollbackErr.Error())
+		}
+		return nil, err
+	}
+	inst.watchInstance(updated)
+	result.Restarted = wasRunning
+	return result, nil
+}
+
+// teardownInstance stops the process or removes the unit, depending on the mode
+func (inst *Controller) teardownInstance(instance *Instance) error {
+	inst.Restarts.Reset(instance.Name)
+	if instance.IsSystemd() {
+		return inst.uninstallInstanceUnit(instance)
+	}
+	return inst.Supervisor.Remove(instance.Name)
+}
+
+func (inst *Controller) setupInstance(instance *Instance, start bool) error {
+	if instance.IsSystemd() {
+		return inst.installInstanceUnit(instance)
+	}
+	if !start {
+		return nil
+	}
+	return inst.StartInstance(instance.Name)
+}
+
+func (inst *Controller) respondInstanceUpdate(c *gin.Context, name string, updated, base *Instance) {
+	result, err := inst.UpdateInstance(name, updated, base, isForced(c))
+	if errors.Is(err, ErrInstanceChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(result.Changes) > 0 {
+		if err = inst.SaveToFile(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	result.Instance = result.Instance.redacted()
+	for _, change := range result.Changes {
+		if change.Field == "secrets" {
+			change.Old = redactSecrets(change.Old.(map[string]string))
+			change.New = redactSecrets(change.New.(map[string]string))
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// UpdateInstanceHandler replaces the whole definition
+func (inst *Controller) UpdateInstanceHandler(c *gin.Context) {
+	var instance *Instance
+	if err := c.ShouldBindJSON(&instance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if instance == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse json"})
+		return
+	}
+	inst.respondInstanceUpdate(c, c.Param("name"), instance, nil)
+}
+
+// PatchInstanceHandler merges the given fields into the stored definition, a concurrent update of the host fails it with a 409
+func (inst *Controller) PatchInstanceHandler(c *gin.Context) {
+	name := c.Param("name")
+	inst.Lock.Lock()
+	existing, err := inst.GetInstance(name)
+	inst.Lock.Unlock()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patched := &Instance{}
+	if err = fieldpatch.Merge(existing, patch, patched); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inst.respondInstanceUpdate(c, name, patched, existing)
+}
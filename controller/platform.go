@@ -1,43 +1,95 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"github.com/NubeIO/lib-files/fileutils"
+	"github.com/NubeIO/platform/logger"
+	"github.com/NubeIO/platform/services/artifact"
+	"github.com/NubeIO/platform/services/hostdb"
+	"github.com/NubeIO/platform/services/hostevents"
+	"github.com/NubeIO/platform/services/installer"
+	"github.com/NubeIO/platform/services/limits"
+	"github.com/NubeIO/platform/services/probe"
+	"github.com/NubeIO/platform/services/restart"
+	"github.com/NubeIO/platform/services/supervisor"
 	"github.com/gin-gonic/gin"
-	"gopkg.in/yaml.v3"
 	"net/http"
 	"os"
+	"path"
+	"strconv"
+	"strings"
 )
 
 const DB = "db.yaml"
 
+const (
+	ModeProcess = "process" // supervised by the platform itself
+	ModeSystemd = "systemd" // runs as nubeio-<name>.service
+)
+
 type Instance struct {
-	Name                        string   `json:"name" yaml:"name"`
-	Repo                        string   `json:"repo" yaml:"repo"`
-	Description                 string   `json:"description" yaml:"description"`
-	Port                        int      `json:"port,omitempty" yaml:"port,omitempty"`
-	Transport                   string   `json:"transport" yaml:"transport"`
-	ExecStart                   string   `json:"execStart" yaml:"exec_start"`
-	AttachWorkingDirOnExecStart bool     `json:"attachWorkingDirOnExecStart" yaml:"attach_working_dir_on_exec_start"`
-	EnvironmentVars             []string `json:"environmentVars" yaml:"environment_vars"`
-	Products                    []string `json:"products" yaml:"products"`
-	Arch                        []string `json:"arch" yaml:"arch"`
-}
-
-func (inst *Controller) LoadFromFile(filePath string) error {
+	Name                        string            `json:"name" yaml:"name"`
+	Repo                        string            `json:"repo" yaml:"repo"`
+	Version                     string            `json:"version,omitempty" yaml:"version,omitempty"`
+	Artifact                    string            `json:"artifact,omitempty" yaml:"artifact,omitempty"`
+	Checksum                    string            `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	Description                 string            `json:"description" yaml:"description"`
+	Port                        int               `json:"port,omitempty" yaml:"port,omitempty"`
+	Transport                   string            `json:"transport" yaml:"transport"`
+	ExecStart                   string            `json:"execStart" yaml:"exec_start"`
+	AttachWorkingDirOnExecStart bool              `json:"attachWorkingDirOnExecStart" yaml:"attach_working_dir_on_exec_start"`
+	EnvironmentVars             []string          `json:"environmentVars" yaml:"environment_vars"`
+	Secrets                     map[string]string `json:"secrets,omitempty" yaml:"secrets,omitempty"` // encrypted at rest, redacted in the responses
+	Products                    []string          `json:"products" yaml:"products"`
+	Arch                        []string          `json:"arch" yaml:"arch"`
+	Mode                        string            `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Liveness                    *probe.Config     `json:"liveness,omitempty" yaml:"liveness,omitempty"`
+	Readiness                   *probe.Config     `json:"readiness,omitempty" yaml:"readiness,omitempty"`
+	Restart                     *restart.Policy   `json:"restart,omitempty" yaml:"restart,omitempty"`
+	Limits                      *limits.Limits    `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+func (instance *Instance) IsSystemd() bool {
+	return instance.Mode == ModeSystemd
+}
+
+type instanceDB struct {
+	Version   int                  `yaml:"version"`
+	Instances map[string]*Instance `yaml:"instances"`
+}
+
+// LoadFromFile loads the instances from the data dir, the legacy file is only read when the data dir has no db yet
+func (inst *Controller) LoadFromFile(legacyFilePath string) error {
 	inst.Lock.Lock()
 	defer inst.Lock.Unlock()
 
-	yamlFile, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
+	db := &instanceDB{}
+	err := inst.DB.Load(db)
+	if os.IsNotExist(err) {
+		yamlFile, legacyErr := os.ReadFile(legacyFilePath)
+		if os.IsNotExist(legacyErr) {
+			inst.Instances = make(map[string]*Instance)
+			return nil
+		}
+		if legacyErr != nil {
+			return legacyErr
+		}
+		logger.Logger.Infof("migrating hosts from %s to %s", legacyFilePath, inst.DB.Path)
+		if err = hostdb.Decode(yamlFile, db); err != nil {
+			return err
+		}
+		db.Version = hostdb.SchemaVersion
+		err = inst.DB.Save(db)
 	}
-
-	inst.Instances = make(map[string]*Instance)
-	err = yaml.Unmarshal(yamlFile, &inst.Instances)
 	if err != nil {
 		return err
 	}
 
+	inst.Instances = db.Instances
+	if inst.Instances == nil {
+		inst.Instances = make(map[string]*Instance)
+	}
 	return nil
 }
 
@@ -45,20 +97,23 @@ func (inst *Controller) SaveToFile() error {
 	inst.Lock.Lock()
 	defer inst.Lock.Unlock()
 
-	yamlData, err := yaml.Marshal(&inst.Instances)
-	if err != nil {
+	return inst.DB.Save(&instanceDB{Version: hostdb.SchemaVersion, Instances: inst.Instances})
+}
+
+func (inst *Controller) AddInstance(instance *Instance, force bool) error {
+	if err := validateInstance(instance); err != nil {
 		return err
 	}
-
-	err = os.WriteFile(DB, yamlData, 0644)
-	if err != nil {
+	if !force {
+		if err := inst.checkInstanceCompatibility(instance); err != nil {
+			return err
+		}
+	}
+	// the download can take minutes, it happens before taking the lock which every host call needs
+	if err := inst.ensureInstanceArtifact(instance); err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (inst *Controller) AddInstance(instance *Instance) error {
 	inst.Lock.Lock()
 	defer inst.Lock.Unlock()
 	name := instance.Name
@@ -66,12 +121,60 @@ func (inst *Controller) AddInstance(instance *Instance) error {
 	if exists {
 		return fmt.Errorf("instance with name %s already exists", name)
 	}
+	if err := inst.sealSecrets(instance, nil); err != nil {
+		return err
+	}
+	if err := inst.checkPortConflicts(instance); err != nil {
+		return err
+	}
 
 	inst.Instances[name] = instance
-	err := inst.StartInstance(name)
+	err := inst.setupInstance(instance, true)
 	if err != nil {
+		delete(inst.Instances, name)
 		return err
 	}
+	inst.watchInstance(instance)
+	return nil
+}
+
+func validateInstance(instance *Instance) error {
+	if instance.Name == "" {
+		return errors.New("name can not be empty")
+	}
+	// the name & version end up in the unit, log, download and install paths
+	if err := installer.ValidatePathName("name", instance.Name); err != nil {
+		return err
+	}
+	if instance.Version != "" {
+		if err := installer.ValidatePathName("version", instance.Version); err != nil {
+			return err
+		}
+	}
+	if instance.Mode != "" && instance.Mode != ModeProcess && instance.Mode != ModeSystemd {
+		return fmt.Errorf("mode must be %s or %s", ModeProcess, ModeSystemd)
+	}
+	if instance.Restart != nil {
+		if err := instance.Restart.Validate(); err != nil {
+			return err
+		}
+	}
+	if instance.Limits != nil {
+		if err := instance.Limits.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range []*probe.Config{instance.Liveness, instance.Readiness} {
+		if p == nil {
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if instance.Port == 0 {
+			return errors.New("port is required for liveness & readiness probes")
+		}
+	}
 	return nil
 }
 
@@ -79,27 +182,197 @@ func (inst *Controller) DeleteInstance(name string) error {
 	inst.Lock.Lock()
 	defer inst.Lock.Unlock()
 
-	_, exists := inst.Instances[name]
+	instance, exists := inst.Instances[name]
 	if !exists {
 		return fmt.Errorf("instance with name %s not found", name)
 	}
 
-	delete(inst.Instances, name)
-	err := inst.StopInstance(name)
+	err := inst.teardownInstance(instance)
 	if err != nil {
 		return err
 	}
+	inst.unwatchInstance(name)
+	if err = inst.Logs.Remove(name); err != nil {
+		logger.Logger.Errorf("failed to remove logs of host %s: %s", name, err.Error())
+	}
+	if err = inst.Events.Remove(name); err != nil {
+		logger.Logger.Errorf("failed to remove events of host %s: %s", name, err.Error())
+	}
+	delete(inst.Instances, name)
 	return nil
 }
 
+// StartInstance is a manual start, it cancels any pending restart of a crashed instance
 func (inst *Controller) StartInstance(name string) error {
+	instance, err := inst.GetInstance(name)
+	if err != nil {
+		return err
+	}
+	inst.Restarts.Reset(name)
+	return inst.startInstance(instance)
+}
 
+func (inst *Controller) startInstance(instance *Instance) error {
+	if err := inst.launchInstance(instance); err != nil {
+		inst.recordEvent(instance.Name, hostevents.Event{Type: hostevents.TypeStartFailed, Message: err.Error()})
+		return err
+	}
+	inst.recordEvent(instance.Name, hostevents.Event{Type: hostevents.TypeStart, PID: inst.instancePID(instance)})
 	return nil
 }
 
+func (inst *Controller) launchInstance(instance *Instance) error {
+	if err := inst.ensureInstanceArtifact(instance); err != nil {
+		return err
+	}
+	if instance.IsSystemd() {
+		return inst.SystemCtl.Start(instanceServiceName(instance))
+	}
+	spec, err := inst.instanceSpec(instance)
+	if err != nil {
+		return err
+	}
+	logger.Logger.Infof("starting host %s: %s %s", instance.Name, spec.Command, strings.Join(spec.Args, " "))
+	return inst.Supervisor.Start(spec)
+}
+
 func (inst *Controller) StopInstance(name string) error {
+	instance, err := inst.GetInstance(name)
+	if err != nil {
+		return err
+	}
+	inst.Restarts.Reset(name)
+	logger.Logger.Infof("stopping host %s", name)
+	if instance.IsSystemd() {
+		if err = inst.SystemCtl.Stop(instanceServiceName(instance)); err != nil {
+			return err
+		}
+		inst.recordEvent(name, hostevents.Event{Type: hostevents.TypeStop})
+		return nil
+	}
+	return inst.Supervisor.Stop(name) // the stop event is recorded by instanceExited
+}
 
-	return nil
+// StartAllInstances starts all the stored instances, it's called once on boot
+func (inst *Controller) StartAllInstances() {
+	for _, instance := range inst.GetAllInstances() {
+		inst.watchInstance(instance)
+		if instance.IsSystemd() {
+			inst.migrateSecretEnvFile(instance)
+			continue // systemd takes care of enabled units
+		}
+		if err := inst.StartInstance(instance.Name); err != nil {
+			logger.Logger.Errorf("failed to start host %s: %s", instance.Name, err.Error())
+		}
+	}
+}
+
+func (inst *Controller) GetInstanceStatus(name string) (*supervisor.Status, error) {
+	instance, err := inst.GetInstance(name)
+	if err != nil {
+		return nil, err
+	}
+	if instance.IsSystemd() {
+		return inst.instanceUnitStatus(instance), nil
+	}
+	return inst.Supervisor.Status(name), nil
+}
+
+func (inst *Controller) instancePID(instance *Instance) int {
+	if instance.IsSystemd() {
+		pid, _ := inst.SystemCtl.GetPID(instanceServiceName(instance))
+		return pid
+	}
+	return inst.Supervisor.Status(instance.Name).PID
+}
+
+// instanceCommand splits ExecStart into args, the working directory is the app install path
+func (inst *Controller) instanceCommand(instance *Instance) (args []string, workingDir string, err error) {
+	args = strings.Fields(instance.ExecStart)
+	if len(args) == 0 {
+		return nil, "", errors.New(fmt.Sprintf("exec_start can not be empty for instance %s", instance.Name))
+	}
+	workingDir = inst.Store.Installer.GetAppInstallPath(instance.Name)
+	if instance.Version != "" {
+		workingDir = inst.Store.Installer.GetAppInstallPathWithVersion(instance.Name, instance.Version)
+	}
+	if instance.AttachWorkingDirOnExecStart {
+		args[0] = path.Join(workingDir, args[0])
+	}
+	if !fileutils.DirExists(workingDir) {
+		workingDir = ""
+	}
+	return args, workingDir, nil
+}
+
+// ensureInstanceArtifact fetches & verifies the artifact of instances which have a repo and a version
+func (inst *Controller) ensureInstanceArtifact(instance *Instance) error {
+	if instance.Repo == "" || instance.Version == "" {
+		return nil
+	}
+	_, err := inst.Artifacts.Ensure(inst.instanceArtifact(instance))
+	return err
+}
+
+func (inst *Controller) instanceArtifact(instance *Instance) *artifact.Spec {
+	return &artifact.Spec{
+		Name:     instance.Name,
+		Repo:     instance.Repo,
+		Version:  instance.Version,
+		Arch:     inst.Config.GetArch(),
+		Artifact: instance.Artifact,
+		Checksum: instance.Checksum,
+	}
+}
+
+func (inst *Controller) instanceSpec(instance *Instance) (*supervisor.Spec, error) {
+	args, workingDir, err := inst.instanceCommand(instance)
+	if err != nil {
+		return nil, err
+	}
+	secretEnv, err := inst.secretEnv(instance)
+	if err != nil {
+		return nil, err
+	}
+	if args, err = limitingCommand(args, instance.Limits); err != nil {
+		return nil, err
+	}
+	cgroup, err := limits.Cgroup(instance.Name, instance.Limits)
+	if err != nil {
+		return nil, err
+	}
+	output := inst.Logs.Get(instance.Name)
+	return &supervisor.Spec{
+		Name:    instance.Name,
+		Command: args[0],
+		Args:    args[1:],
+		Dir:     workingDir,
+		Env:     append(append([]string{}, instance.EnvironmentVars...), secretEnv...),
+		Stdout:  output,
+		Stderr:  output,
+		OnExit:  inst.instanceExited(instance.Name),
+		Cgroup:  cgroup,
+	}, nil
+}
+
+// limitingCommand starts the command through the exec command of the platform, which sets the nice level and the
+// open files rlimit before the exec, so that no child of the process is forked before they are applied
+func limitingCommand(args []string, l *limits.Limits) ([]string, error) {
+	if l == nil || (l.Nice == 0 && l.MaxOpenFiles == 0) {
+		return args, nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	command := []string{executable, "exec"}
+	if l.Nice != 0 {
+		command = append(command, "--nice", strconv.Itoa(l.Nice))
+	}
+	if l.MaxOpenFiles > 0 {
+		command = append(command, "--max-open-files", strconv.FormatUint(l.MaxOpenFiles, 10))
+	}
+	return append(append(command, "--"), args...), nil
 }
 
 func (inst *Controller) RestartInstance(name string) error {
@@ -139,7 +412,7 @@ func (inst *Controller) CreateInstance(c *gin.Context) {
 		return
 	}
 
-	err := inst.AddInstance(instance)
+	err := inst.AddInstance(instance, isForced(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -156,7 +429,10 @@ func (inst *Controller) CreateInstance(c *gin.Context) {
 
 func (inst *Controller) StartInstanceHandler(c *gin.Context) {
 	name := c.Param("name")
-	err := inst.StartInstance(name)
+	err := inst.CheckInstanceCompatibility(name, isForced(c))
+	if err == nil {
+		err = inst.StartInstance(name)
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -174,9 +450,22 @@ func (inst *Controller) StopInstanceHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Instance stopped successfully"})
 }
 
+func (inst *Controller) GetInstanceStatusHandler(c *gin.Context) {
+	name := c.Param("name")
+	status, err := inst.GetInstanceStatus(name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
 func (inst *Controller) RestartInstanceHandler(c *gin.Context) {
 	name := c.Param("name")
-	err := inst.RestartInstance(name)
+	err := inst.CheckInstanceCompatibility(name, isForced(c))
+	if err == nil {
+		err = inst.RestartInstance(name)
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -208,16 +497,16 @@ func (inst *Controller) GetInstancesHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"instances": instance})
+	c.JSON(http.StatusOK, gin.H{"instances": instance.redacted()})
 }
 
 func (inst *Controller) GetAllInstancesHandler(c *gin.Context) {
 	instances := inst.GetAllInstances()
-	c.JSON(http.StatusOK, gin.H{"instances": instances})
+	c.JSON(http.StatusOK, gin.H{"instances": redactInstances(instances)})
 }
 
 func (inst *Controller) ReadYAMLFile(c *gin.Context) {
-	yamlFile, err := os.ReadFile(DB)
+	yamlFile, err := os.ReadFile(inst.DB.Path)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
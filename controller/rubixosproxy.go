@@ -7,7 +7,13 @@ import (
 )
 
 func (inst *Controller) ROSProxy(c *gin.Context) {
-	remote, err := Builder("0.0.0.0", 1660)
+	proxyTo(c, "0.0.0.0", 1660, c.Param("proxy_path"), nil)
+}
+
+// proxyTo forwards the request to ip:port, connection upgrades (websockets) are passed through by the reverse proxy;
+// rewrite gets the outgoing request once it points to the remote
+func proxyTo(c *gin.Context, ip string, port int, path string, rewrite func(req *http.Request)) {
+	remote, err := Builder(ip, port)
 	if err != nil {
 		responseHandler(nil, err, c)
 		return
@@ -23,7 +29,10 @@ func (inst *Controller) ROSProxy(c *gin.Context) {
 		req.Host = remote.Host
 		req.URL.Scheme = remote.Scheme
 		req.URL.Host = remote.Host
-		req.URL.Path = c.Param("proxy_path")
+		req.URL.Path = path
+		if rewrite != nil {
+			rewrite(req)
+		}
 	}
 	proxy.ServeHTTP(c.Writer, c.Request)
 }
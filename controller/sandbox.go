@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"errors"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// resolvePath checks the path against the allowed roots, the response is already sent when it returns false
+func (inst *Controller) resolvePath(c *gin.Context, p string, access sandbox.Access) (string, bool) {
+	resolved, err := inst.Sandbox.Resolve(p, access)
+	return resolved, respondForbidden(c, err)
+}
+
+// resolveTree is resolvePath for copying, moving or deleting a whole tree, which can't contain a denied path
+func (inst *Controller) resolveTree(c *gin.Context, p string, access sandbox.Access) (string, bool) {
+	resolved, err := inst.Sandbox.ResolveTree(p, access)
+	return resolved, respondForbidden(c, err)
+}
+
+func respondForbidden(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, sandbox.ErrForbidden) {
+		responseHandler(nil, err, c, http.StatusForbidden)
+	} else {
+		responseHandler(nil, err, c)
+	}
+	return false
+}
@@ -2,12 +2,17 @@ package controller
 
 import (
 	"github.com/NubeIO/platform/model"
+	"github.com/NubeIO/platform/services/sandbox"
 	"github.com/gin-gonic/gin"
+	"path/filepath"
 	"syscall"
 )
 
 func (inst *Controller) SyscallUnlink(c *gin.Context) {
-	path := c.Query("path")
+	path, ok := inst.resolvePath(c, c.Query("path"), sandbox.Remove)
+	if !ok {
+		return
+	}
 	err := syscall.Unlink(path)
 	if err != nil {
 		responseHandler(nil, err, c)
@@ -19,6 +24,18 @@ func (inst *Controller) SyscallUnlink(c *gin.Context) {
 func (inst *Controller) SyscallLink(c *gin.Context) {
 	path := c.Query("path")
 	link := c.Query("link")
+	// the link is created as an entry, a relative target is relative to the link dir
+	link, ok := inst.resolvePath(c, link, sandbox.Remove)
+	if !ok {
+		return
+	}
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(link), path)
+	}
+	path, ok = inst.resolvePath(c, path, sandbox.Read)
+	if !ok {
+		return
+	}
 	err := syscall.Symlink(path, link)
 	if err != nil {
 		responseHandler(nil, err, c)
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/nubeio-rubix-lib-auth-go/auth"
+	"github.com/NubeIO/platform/model"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/NubeIO/platform/services/trash"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// caller is recorded on the trash items, the token user when there is one and the client ip otherwise
+func caller(c *gin.Context) string {
+	if authorization := auth.GetAuthorization(c.Request); len(authorization) > 0 {
+		if authorization[0] == "Internal" || authorization[0] == "External" {
+			return strings.ToLower(authorization[0])
+		}
+	}
+	if username, err := auth.GetAuthorizedUsername(c.Request); err == nil && username != "" {
+		return username
+	}
+	return c.ClientIP()
+}
+
+// removePath moves the path into the trash, ?permanent=true removes it for real
+func (inst *Controller) removePath(c *gin.Context, p string) (string, error) {
+	if !inst.Trash.Enabled || c.Query("permanent") == "true" {
+		return fmt.Sprintf("deleted: %s", p), os.RemoveAll(p)
+	}
+	item, err := inst.Trash.Move(p, caller(c))
+	if errors.Is(err, trash.ErrTooBig) {
+		return "", errors.New(fmt.Sprintf("%s, nothing was deleted, add ?permanent=true to delete it for real", err.Error()))
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("moved to the trash: %s, restore it with id %s", p, item.ID), nil
+}
+
+func trashStatus(err error) int {
+	switch {
+	case errors.Is(err, trash.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, trash.ErrExists):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (inst *Controller) ListTrash(c *gin.Context) {
+	items, err := inst.Trash.List()
+	responseHandler(items, err, c)
+}
+
+// RestoreTrash moves the item back to where it was deleted from, or to ?destination=
+func (inst *Controller) RestoreTrash(c *gin.Context) {
+	item, err := inst.Trash.Get(c.Param("id"))
+	if err != nil {
+		responseHandler(nil, err, c, trashStatus(err))
+		return
+	}
+	destination := c.Query("destination")
+	if destination == "" {
+		destination = item.OriginalPath
+	}
+	destination, ok := inst.resolvePath(c, destination, sandbox.Write)
+	if !ok {
+		return
+	}
+	item, err = inst.Trash.Restore(item.ID, destination)
+	if err != nil {
+		responseHandler(nil, err, c, trashStatus(err))
+		return
+	}
+	responseHandler(item, nil, c, http.StatusOK)
+}
+
+func (inst *Controller) PurgeTrashItem(c *gin.Context) {
+	if err := inst.Trash.Purge(c.Param("id")); err != nil {
+		responseHandler(nil, err, c, trashStatus(err))
+		return
+	}
+	responseHandler(model.Message{Message: fmt.Sprintf("purged trash item: %s", c.Param("id"))}, nil, c)
+}
+
+func (inst *Controller) PurgeTrash(c *gin.Context) {
+	n, err := inst.Trash.PurgeAll()
+	responseHandler(model.Message{Message: fmt.Sprintf("purged %d trash items", n)}, err, c)
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/dto"
+	"github.com/NubeIO/platform/model"
+	"github.com/NubeIO/platform/services/uploads"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CreateUpload struct {
+	FileName string `json:"fileName"`
+	Size     int64  `json:"size"`
+}
+
+func uploadStatus(err error) int {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, uploads.ErrOffset):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondUpload(c *gin.Context, session *uploads.Session, err error) {
+	if session != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	}
+	if err != nil {
+		status := uploadStatus(err)
+		if errors.Is(err, uploads.ErrOffset) && session != nil {
+			err = errors.New(fmt.Sprintf("%s, resume from %d", err.Error(), session.Offset))
+		}
+		responseHandler(nil, err, c, status)
+		return
+	}
+	responseHandler(session, nil, c, http.StatusOK)
+}
+
+// CreateUploadHandler starts a resumable upload, the chunks are then sent with PUT
+func (inst *Controller) CreateUploadHandler(c *gin.Context) {
+	var body *CreateUpload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	session, err := inst.Uploads.Create(body.FileName, body.Size)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return
+	}
+	responseHandler(session, nil, c)
+}
+
+// GetUploadHandler returns the session, its offset is where an interrupted upload resumes from
+func (inst *Controller) GetUploadHandler(c *gin.Context) {
+	session, err := inst.Uploads.Get(c.Param("id"))
+	respondUpload(c, session, err)
+}
+
+// WriteUploadHandler appends the body at the offset of the `Content-Range: bytes <start>-<end>/<size>` header or of ?offset=
+func (inst *Controller) WriteUploadHandler(c *gin.Context) {
+	if contentRange := c.GetHeader("Content-Range"); contentRange != "" {
+		var start, end, size int64
+		if _, err := fmt.Sscanf(strings.TrimSpace(contentRange), "bytes %d-%d/%d", &start, &end, &size); err != nil {
+			responseHandler(nil, errors.New(fmt.Sprintf("invalid Content-Range %s, try bytes 0-1023/4096", contentRange)), c)
+			return
+		}
+		session, err := inst.Uploads.WriteRange(c.Param("id"), start, end, size, c.Request.Body)
+		respondUpload(c, session, err)
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil {
+		responseHandler(nil, errors.New("offset must be a number"), c)
+		return
+	}
+	session, err := inst.Uploads.Write(c.Param("id"), offset, c.Request.Body)
+	respondUpload(c, session, err)
+}
+
+// FinalizeUploadHandler checks the size and the ?checksum=<algo>:<hex>, the upload_id can then be given to the upload APIs
+func (inst *Controller) FinalizeUploadHandler(c *gin.Context) {
+	session, err := inst.Uploads.Finalize(c.Param("id"), c.Query("checksum"))
+	respondUpload(c, session, err)
+}
+
+func (inst *Controller) DeleteUploadHandler(c *gin.Context) {
+	err := inst.Uploads.Remove(c.Param("id"))
+	if err != nil {
+		responseHandler(nil, err, c, uploadStatus(err))
+		return
+	}
+	responseHandler(model.Message{Message: "upload deleted"}, nil, c)
+}
+
+// storeUpload fills the upload from ?upload_id= or from the form file and takes the expected ?checksum=,
+// the returned func removes the session and is only called on success, so a failed step can be retried with the same upload
+func (inst *Controller) storeUpload(c *gin.Context, m *dto.Upload) (func(), error) {
+	m.Checksum = c.Query("checksum")
+	uploadID := c.Query("upload_id")
+	if uploadID == "" {
+		file, err := c.FormFile("file")
+		m.File = file
+		return func() {}, err
+	}
+	localFile, err := inst.Uploads.Complete(uploadID)
+	if err != nil {
+		return func() {}, err
+	}
+	m.LocalFile = localFile
+	return func() { _ = inst.Uploads.Remove(uploadID) }, nil
+}
@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"archive/zip"
 	"errors"
 	"fmt"
 	"github.com/NubeIO/lib-files/fileutils"
 	"github.com/NubeIO/platform/model"
+	"github.com/NubeIO/platform/services/archive"
+	"github.com/NubeIO/platform/services/sandbox"
 	"github.com/gin-gonic/gin"
 	"os"
 	"path/filepath"
@@ -22,6 +25,17 @@ func (inst *Controller) Unzip(c *gin.Context) {
 		responseHandler(nil, errors.New("zip destination can not be empty, try /data/unzip-test"), c)
 		return
 	}
+	pathToZip, ok := inst.resolvePath(c, pathToZip, sandbox.Read)
+	if !ok {
+		return
+	}
+	destination, ok = inst.resolvePath(c, destination, sandbox.Write)
+	if !ok {
+		return
+	}
+	if !inst.checkUnzipEntries(c, pathToZip, destination) {
+		return
+	}
 	zip, err := fileutils.Unzip(pathToZip, destination, os.FileMode(inst.FileMode))
 	if err != nil {
 		responseHandler(nil, err, c)
@@ -42,6 +56,14 @@ func (inst *Controller) ZipDir(c *gin.Context) {
 		responseHandler(nil, errors.New("zip destination can not be empty, try /data/test/flow-framework.zip"), c)
 		return
 	}
+	pathToZip, ok := inst.resolvePath(c, pathToZip, sandbox.Read)
+	if !ok {
+		return
+	}
+	destination, ok = inst.resolvePath(c, destination, sandbox.Write)
+	if !ok {
+		return
+	}
 	exists := fileutils.DirExists(pathToZip)
 	if !exists {
 		responseHandler(nil, errors.New("zip source is not found"), c)
@@ -52,10 +74,40 @@ func (inst *Controller) ZipDir(c *gin.Context) {
 		responseHandler(nil, err, c)
 		return
 	}
-	err = fileutils.RecursiveZip(pathToZip, destination)
+	// the denied paths are left out, and so is the zip itself when it's written into the source dir
+	err = zipDir(pathToZip, destination, func(p string) bool { return p == destination || inst.Sandbox.Hidden(p) })
 	if err != nil {
 		responseHandler(nil, err, c)
 		return
 	}
 	responseHandler(model.Message{Message: fmt.Sprintf("zip file is created on: %s", destination)}, nil, c)
 }
+
+// checkUnzipEntries refuses archives with entries which would land on a denied or read-only path
+func (inst *Controller) checkUnzipEntries(c *gin.Context, pathToZip, destination string) bool {
+	reader, err := zip.OpenReader(pathToZip)
+	if err != nil {
+		responseHandler(nil, err, c)
+		return false
+	}
+	defer reader.Close()
+	for _, f := range reader.File {
+		if _, ok := inst.resolvePath(c, filepath.Join(destination, f.Name), sandbox.Write); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func zipDir(source, destination string, skip func(p string) bool) error {
+	f, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	if err = archive.Write(f, source, archive.Zip, &archive.Filter{Skip: skip}); err != nil {
+		_ = f.Close()
+		_ = os.Remove(destination)
+		return err
+	}
+	return f.Close()
+}
@@ -37,6 +37,8 @@ type Upload struct {
 	MoveExtractedFileToNameApp      bool                  `json:"move_extracted_file_to_name_app"`
 	MoveOneLevelInsideFileToOutside bool                  `json:"move_one_level_inside_file_to_outside"`
 	File                            *multipart.FileHeader `json:"file"`
+	LocalFile                       string                `json:"local_file"` // already on the device, e.g. a finalized chunked upload, used when File is nil
+	Checksum                        string                `json:"checksum"`   // expected <algo>:<hex>, the upload is rejected when it doesn't match
 }
 
 type UploadResponse struct {
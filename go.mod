@@ -14,12 +14,15 @@ require (
 	github.com/NubeIO/nubeio-rubix-lib-auth-go v1.3.2
 	github.com/NubeIO/nubeio-rubix-lib-helpers-go v0.3.0
 	github.com/gin-contrib/cors v1.7.1
+	github.com/gin-contrib/sse v0.1.0
 	github.com/gin-gonic/gin v1.9.1
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/shirou/gopsutil v3.21.11+incompatible
 	github.com/sirupsen/logrus v1.9.0
 	github.com/spf13/cobra v1.4.0
 	github.com/spf13/viper v1.11.0
+	golang.org/x/net v0.22.0
+	golang.org/x/sys v0.19.0
 )
 
 require (
@@ -40,8 +43,7 @@ require (
 
 require (
 	github.com/NubeIO/lib-uuid v0.0.3
-	github.com/fsnotify/fsnotify v1.5.1 // indirect
-	github.com/gin-contrib/sse v0.1.0 // indirect
+	github.com/fsnotify/fsnotify v1.5.1
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/go-playground/validator/v10 v10.19.0 // indirect
@@ -65,8 +67,6 @@ require (
 	github.com/subosito/gotenv v1.2.0 // indirect
 	github.com/ugorji/go/codec v1.2.12 // indirect
 	golang.org/x/crypto v0.22.0 // indirect
-	golang.org/x/net v0.22.0 // indirect
-	golang.org/x/sys v0.19.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/protobuf v1.33.0 // indirect
 	gopkg.in/ini.v1 v1.66.4 // indirect
@@ -10,8 +10,21 @@ import (
 	"github.com/NubeIO/platform/logger"
 	"github.com/NubeIO/platform/model"
 	"github.com/NubeIO/platform/services/appstore"
+	"github.com/NubeIO/platform/services/artifact"
+	"github.com/NubeIO/platform/services/hostdb"
+	"github.com/NubeIO/platform/services/hostevents"
+	"github.com/NubeIO/platform/services/hostlog"
 	"github.com/NubeIO/platform/services/info"
+	"github.com/NubeIO/platform/services/probe"
+	"github.com/NubeIO/platform/services/restart"
+	"github.com/NubeIO/platform/services/rubixregistry"
+	"github.com/NubeIO/platform/services/sandbox"
+	"github.com/NubeIO/platform/services/secrets"
+	"github.com/NubeIO/platform/services/supervisor"
 	systeminfo "github.com/NubeIO/platform/services/system"
+	"github.com/NubeIO/platform/services/trash"
+	"github.com/NubeIO/platform/services/unitfile"
+	"github.com/NubeIO/platform/services/uploads"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
@@ -19,6 +32,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"sync"
 	"time"
 )
@@ -66,16 +80,55 @@ func Setup() *gin.Engine {
 		SystemCtl:  systemCtl,
 		FileMode:   0755,
 		Instances:  make(map[string]*controller.Instance),
+		DB:         hostdb.New(path.Join(config.Config.GetAbsDataDir(), controller.DB), viper.GetInt("hosts.db.backups")),
 		Lock:       sync.Mutex{},
 		Config:     config.Config,
 		SystemInfo: systemInfo,
 		Networking: info.New(&info.System{}),
 		Store:      appstore.New(fmt.Sprintf("/%s", config.Config.GetAbsDataDir())),
+		Supervisor: supervisor.New(10 * time.Second),
+		Units:      unitfile.New(systemCtl),
+		Probes:     probe.NewMonitor(),
+		Logs: hostlog.New(
+			path.Join(config.Config.GetAbsDataDir(), "hosts", "logs"),
+			viper.GetInt64("hosts.log.max_size_mb")*1024*1024,
+			viper.GetInt("hosts.log.max_backups"),
+		),
+		Events:   hostevents.New(path.Join(config.Config.GetAbsDataDir(), "hosts", "events"), viper.GetInt("hosts.events.max")),
+		Restarts: restart.NewScheduler(),
+		Registry: rubixregistry.New(config.Config.GetRootDir()),
 	}
-	err := api.LoadFromFile("./db.yaml")
+	api.Artifacts = artifact.New(api.Store.Installer)
+	api.MaxImportBytes = viper.GetInt64("hosts.import.max_size_mb") * 1024 * 1024
+	api.Uploads = uploads.New(path.Join(api.Store.Installer.TmpDir, "uploads"), time.Duration(viper.GetInt("uploads.expiry_hours"))*time.Hour)
+	go api.Uploads.RunGC(time.Hour)
+	api.Trash = trash.New(
+		path.Join(config.Config.GetAbsDataDir(), "trash"),
+		time.Duration(viper.GetInt("trash.max_age_days"))*24*time.Hour,
+		int64(viper.GetInt("trash.max_size_mb"))*1024*1024,
+	)
+	api.Trash.Enabled = viper.GetBool("trash.enabled")
+	go api.Trash.RunExpiry(time.Hour)
+	api.Secrets = secrets.New(path.Join(config.Config.GetAbsDataDir(), "keys", "secrets.key"))
+	allowedRoots := viper.GetStringSlice("files.allowed_roots")
+	if len(allowedRoots) == 0 {
+		allowedRoots = []string{config.Config.GetAbsDataDir()}
+	}
+	sandboxed, err := sandbox.New(allowedRoots, viper.GetStringSlice("files.read_only_roots"))
 	if err != nil {
 		log.Fatal(err)
 	}
+	// the keys & the env files older versions decrypted the host secrets into live in the data dir as well, the trash only goes through its own api
+	err = sandboxed.Deny(path.Join(config.Config.GetAbsDataDir(), "keys"), path.Join(config.Config.GetAbsDataDir(), "hosts", "secrets"), api.Trash.Dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	api.Sandbox = sandboxed
+	err = api.LoadFromFile("./db.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	go api.StartAllInstances()
 	engine.POST("/api/users/login", api.Login)
 	systemApi := engine.Group("/api/system")
 	{
@@ -93,6 +146,9 @@ func Setup() *gin.Engine {
 	apiProxyROSRoutes := engine.Group("/ros")
 	apiProxyROSRoutes.Any("/*proxy_path", api.ROSProxy)
 
+	apiProxyHostRoutes := engine.Group("/hosts", handleAuth)
+	apiProxyHostRoutes.Any("/:name/proxy/*path", api.InstanceProxy)
+
 	apiRoutes := engine.Group("/api", handleAuth)
 
 	systemRoutes := apiRoutes.Group("/system")
@@ -140,15 +196,45 @@ func Setup() *gin.Engine {
 		files.POST("/upload", api.UploadFile)           // upload single file
 		files.POST("/download", api.DownloadFile)       // download single file
 		files.GET("/read", api.ReadFile)                // read single file
+		files.GET("/tail", api.TailFile)                // last lines of single file
+		files.GET("/follow", api.FollowFile)            // stream appended lines of single file over sse
+		files.GET("/range", api.ReadFileRange)          // read bytes of single file by offset & length
+		files.GET("/checksum", api.ChecksumFile)        // sha256, sha1 or md5 of single file
+		files.GET("/search", api.SearchFiles)           // search content of files, similar as grep in linux command
+		files.GET("/watch", api.WatchFiles)             // stream file changes over sse or websocket
 		files.PUT("/write", api.WriteFile)              // write single file
 		files.DELETE("/delete", api.DeleteFile)         // delete single file
 		files.DELETE("/delete-all", api.DeleteAllFiles) // deletes file or folder
 	}
 
+	configRoutes := apiRoutes.Group("/configs")
+	{
+		configRoutes.GET("/:app/:configName", api.GetAppConfig)
+		configRoutes.PATCH("/:app/:configName", api.PatchAppConfig)
+	}
+
+	trashRoutes := apiRoutes.Group("/trash")
+	{
+		trashRoutes.GET("", api.ListTrash)
+		trashRoutes.POST("/:id/restore", api.RestoreTrash)
+		trashRoutes.DELETE("/:id", api.PurgeTrashItem)
+		trashRoutes.DELETE("", api.PurgeTrash)
+	}
+
+	uploadRoutes := apiRoutes.Group("/uploads")
+	{
+		uploadRoutes.POST("", api.CreateUploadHandler)
+		uploadRoutes.GET("/:id", api.GetUploadHandler)
+		uploadRoutes.PUT("/:id", api.WriteUploadHandler)
+		uploadRoutes.POST("/:id/finalize", api.FinalizeUploadHandler)
+		uploadRoutes.DELETE("/:id", api.DeleteUploadHandler)
+	}
+
 	dirs := apiRoutes.Group("/dirs")
 	{
-		dirs.GET("/exists", api.DirExists)  // needs to be a folder
-		dirs.POST("/create", api.CreateDir) // create folder
+		dirs.GET("/exists", api.DirExists)     // needs to be a folder
+		dirs.POST("/create", api.CreateDir)    // create folder
+		dirs.GET("/download", api.DownloadDir) // stream folder as zip or tar.gz
 	}
 
 	zip := apiRoutes.Group("/zip")
@@ -245,11 +331,22 @@ func Setup() *gin.Engine {
 	}
 
 	apiRoutes.GET("/hosts", api.GetAllInstancesHandler)
+	apiRoutes.GET("/hosts/stats", api.GetAllInstancesStatsHandler)
+	apiRoutes.GET("/hosts/export", api.ExportHostsHandler)
+	apiRoutes.POST("/hosts/import", api.ImportHostsHandler)
 	apiRoutes.GET("/hosts/:name", api.GetInstancesHandler)
 	apiRoutes.POST("/hosts", api.CreateInstance)
-	apiRoutes.GET("/hosts/start", api.StartInstanceHandler)
-	apiRoutes.GET("/hosts/stop", api.StopInstanceHandler)
-	apiRoutes.GET("/hosts/restart", api.RestartInstanceHandler)
+	apiRoutes.PUT("/hosts/:name", api.UpdateInstanceHandler)
+	apiRoutes.PATCH("/hosts/:name", api.PatchInstanceHandler)
+	apiRoutes.GET("/hosts/:name/status", api.GetInstanceStatusHandler)
+	apiRoutes.GET("/hosts/:name/health", api.GetInstanceHealthHandler)
+	apiRoutes.GET("/hosts/:name/logs", api.GetInstanceLogsHandler)
+	apiRoutes.GET("/hosts/:name/logs/stream", api.StreamInstanceLogsHandler)
+	apiRoutes.GET("/hosts/:name/events", api.GetInstanceEventsHandler)
+	apiRoutes.GET("/hosts/:name/stats", api.GetInstanceStatsHandler)
+	apiRoutes.POST("/hosts/:name/start", api.StartInstanceHandler)
+	apiRoutes.POST("/hosts/:name/stop", api.StopInstanceHandler)
+	apiRoutes.POST("/hosts/:name/restart", api.RestartInstanceHandler)
 	apiRoutes.DELETE("/hosts/:name", api.DeleteInstanceHandler)
 
 	return engine
@@ -6,6 +6,7 @@ import (
 	"github.com/NubeIO/lib-files/fileutils"
 	"github.com/NubeIO/lib-utils-go/nversion"
 	"github.com/NubeIO/platform/dto"
+	"github.com/NubeIO/platform/utils/checksum"
 	"io/fs"
 	"os"
 	"path"
@@ -21,6 +22,9 @@ func (inst *Store) GetModulesStoreModules() ([]dto.Module, error) {
 		if err != nil {
 			return err
 		}
+		if checksum.IsSidecar(p) {
+			return nil
+		}
 		files = append(files, p)
 		return nil
 	})
@@ -44,9 +48,8 @@ func (inst *Store) GetModulesStoreModules() ([]dto.Module, error) {
 }
 
 func (inst *Store) UploadModuleStoreModule(app *dto.Upload) (*UploadResponse, error) {
-	var file = app.File
 	uploadResponse := &UploadResponse{}
-	resp, err := inst.Installer.Upload(file)
+	resp, err := inst.Installer.UploadApp(app)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("upload module: %s", err.Error()))
 	}
@@ -74,6 +77,10 @@ func (inst *Store) UploadModuleStoreModule(app *dto.Upload) (*UploadResponse, er
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("move module error: %s", err.Error()))
 	}
+	uploadResponse.Checksum, err = checksum.WriteSidecar(destination)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("write checksum error: %s", err.Error()))
+	}
 	uploadResponse.UploadedOk = true
 	return uploadResponse, nil
 }
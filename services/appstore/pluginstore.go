@@ -6,6 +6,7 @@ import (
 	"github.com/NubeIO/lib-files/fileutils"
 	"github.com/NubeIO/platform/dto"
 	"github.com/NubeIO/platform/services/installer"
+	"github.com/NubeIO/platform/utils/checksum"
 	"io/ioutil"
 	"os"
 )
@@ -19,15 +20,17 @@ func (inst *Store) GetPluginsStorePlugins() ([]installer.BuildDetails, error) {
 	}
 	plugins := make([]installer.BuildDetails, 0)
 	for _, file := range files {
+		if checksum.IsSidecar(file.Name()) {
+			continue
+		}
 		plugins = append(plugins, *inst.Installer.GetZipBuildDetails(file.Name()))
 	}
 	return plugins, err
 }
 
 func (inst *Store) UploadPluginStorePlugin(app *dto.Upload) (*UploadResponse, error) {
-	var file = app.File
 	uploadResponse := &UploadResponse{}
-	resp, err := inst.Installer.Upload(file)
+	resp, err := inst.Installer.UploadApp(app)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("upload plugin: %s", err.Error()))
 	}
@@ -45,6 +48,10 @@ func (inst *Store) UploadPluginStorePlugin(app *dto.Upload) (*UploadResponse, er
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("move plugin error: %s", err.Error()))
 	}
+	uploadResponse.Checksum, err = checksum.WriteSidecar(destination)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("write checksum error: %s", err.Error()))
+	}
 	uploadResponse.UploadedOk = true
 	return uploadResponse, nil
 }
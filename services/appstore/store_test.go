@@ -0,0 +1,41 @@
+package appstore
+
+import (
+	"github.com/NubeIO/platform/utils/checksum"
+	"os"
+	"path"
+	"testing"
+)
+
+func storeFile(t *testing.T, filePath string) {
+	if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte("zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checksum.WriteSidecar(filePath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreListingsHideSidecars(t *testing.T) {
+	store := New(t.TempDir())
+	storeFile(t, store.Installer.GetPluginsStoreWithFile("bacnet-1.0.0-abc123.amd64.zip"))
+	storeFile(t, path.Join(store.Installer.GetModulesStoreWithModuleVersionFolder("module-core-rql", "v1.0.0"), "module-core-rql-amd64"))
+
+	plugins, err := store.GetPluginsStorePlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 || plugins[0].ZipName != "bacnet-1.0.0-abc123.amd64.zip" {
+		t.Fatalf("expected the plugin without its sidecar, got %+v", plugins)
+	}
+	modules, err := store.GetModulesStoreModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 1 || modules[0].Name != "module-core-rql" || modules[0].Version != "v1.0.0" {
+		t.Fatalf("expected the module without its sidecar, got %+v", modules)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/NubeIO/lib-files/fileutils"
 	"github.com/NubeIO/platform/dto"
+	"github.com/NubeIO/platform/utils/checksum"
 	"os"
 	"path"
 )
@@ -15,6 +16,7 @@ type UploadResponse struct {
 	UploadedOk   bool   `json:"uploadedOk,omitempty"`
 	TmpFile      string `json:"tmpFile,omitempty"`
 	UploadedFile string `json:"uploadedFile,omitempty"`
+	Checksum     string `json:"checksum,omitempty"`
 }
 
 func (inst *Store) UploadAddOnAppStore(app *dto.Upload) (*UploadResponse, error) {
@@ -31,7 +33,6 @@ func (inst *Store) UploadAddOnAppStore(app *dto.Upload) (*UploadResponse, error)
 	if err != nil {
 		return nil, err
 	}
-	var file = app.File
 	uploadResp := &UploadResponse{
 		Name:         app.Name,
 		Version:      app.Version,
@@ -39,7 +40,7 @@ func (inst *Store) UploadAddOnAppStore(app *dto.Upload) (*UploadResponse, error)
 		TmpFile:      "",
 		UploadedFile: "",
 	}
-	resp, err := inst.Installer.Upload(file)
+	resp, err := inst.Installer.UploadApp(app)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("upload app: %s", err.Error()))
 	}
@@ -56,6 +57,10 @@ func (inst *Store) UploadAddOnAppStore(app *dto.Upload) (*UploadResponse, error)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("move build error: %s", err.Error()))
 	}
+	uploadResp.Checksum, err = checksum.WriteSidecar(destination)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("write checksum error: %s", err.Error()))
+	}
 	uploadResp.UploadedOk = true
 	return uploadResp, nil
 }
@@ -0,0 +1,203 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	Zip   = "zip"
+	TarGz = "tar.gz"
+)
+
+// Filter picks the entries of the archive, globs are matched against the path relative to the root and against the base name
+type Filter struct {
+	Include []string // files only, when empty every file is included
+	Exclude []string // files & dirs, an excluded dir is skipped as a whole
+	Skip    func(p string) bool
+}
+
+func (f *Filter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.New(fmt.Sprintf("invalid glob %s", pattern))
+		}
+	}
+	return nil
+}
+
+func matches(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ContentType(format string) string {
+	if format == TarGz {
+		return "application/gzip"
+	}
+	return "application/zip"
+}
+
+type entry struct {
+	name string // slash separated, prefixed with the root dir name
+	path string
+	info fs.FileInfo
+	link string
+}
+
+// Write streams the dir as a zip or tar.gz, nothing is written to disk; symlinks are stored as links and never followed
+func Write(w io.Writer, root, format string, filter *Filter) error {
+	if filter == nil {
+		filter = &Filter{}
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	var add func(e *entry) error
+	var closer func() error
+	switch format {
+	case Zip, "":
+		add, closer = zipWriter(w)
+	case TarGz:
+		add, closer = tarWriter(w)
+	default:
+		return errors.New(fmt.Sprintf("unsupported archive format %s, try %s or %s", format, Zip, TarGz))
+	}
+	base := filepath.Base(root)
+	if base == string(filepath.Separator) {
+		base = "root"
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		isDir := d.IsDir()
+		if rel != "." {
+			if matches(filter.Exclude, rel) || (filter.Skip != nil && filter.Skip(p)) {
+				if isDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !isDir && len(filter.Include) > 0 && !matches(filter.Include, rel) {
+				return nil
+			}
+		}
+		// with include globs dirs are only created implicitly, so unrelated empty dirs are left out
+		if isDir && len(filter.Include) > 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !isDir && !info.Mode().IsRegular() && info.Mode()&fs.ModeSymlink == 0 {
+			return nil // sockets, devices & pipes
+		}
+		e := &entry{name: path.Join(base, rel), path: p, info: info}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if e.link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		return add(e)
+	})
+	if err != nil {
+		_ = closer()
+		return err
+	}
+	return closer()
+}
+
+// copyFile copies size bytes when size >= 0, tar headers can't grow with a file that is still being written to
+func copyFile(w io.Writer, p string, size int64) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if size < 0 {
+		_, err = io.Copy(w, f)
+	} else {
+		_, err = io.CopyN(w, f, size)
+	}
+	return err
+}
+
+func zipWriter(w io.Writer) (func(e *entry) error, func() error) {
+	zw := zip.NewWriter(w)
+	add := func(e *entry) error {
+		header, err := zip.FileInfoHeader(e.info)
+		if err != nil {
+			return err
+		}
+		header.Name = e.name
+		if e.info.IsDir() {
+			header.Name += "/"
+			_, err = zw.CreateHeader(header)
+			return err
+		}
+		if e.link == "" {
+			header.Method = zip.Deflate
+		}
+		out, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if e.link != "" {
+			_, err = out.Write([]byte(e.link))
+			return err
+		}
+		return copyFile(out, e.path, -1)
+	}
+	return add, zw.Close
+}
+
+func tarWriter(w io.Writer) (func(e *entry) error, func() error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(e *entry) error {
+		header, err := tar.FileInfoHeader(e.info, e.link)
+		if err != nil {
+			return err
+		}
+		header.Name = e.name
+		if e.info.IsDir() {
+			header.Name += "/"
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if e.info.Mode().IsRegular() {
+			return copyFile(tw, e.path, header.Size)
+		}
+		return nil
+	}
+	closer := func() error {
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	return add, closer
+}
@@ -0,0 +1,96 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"testing"
+)
+
+func tree(t *testing.T) string {
+	root := path.Join(t.TempDir(), "app")
+	for name, content := range map[string]string{
+		"config/app.yaml": "port: 1660",
+		"data/app.db":     "db",
+		"logs/app.log":    "log",
+		"README.md":       "readme",
+	} {
+		p := path.Join(root, name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestZip(t *testing.T) {
+	root := tree(t)
+	var buffer bytes.Buffer
+	filter := &Filter{Include: []string{"*.yaml", "*.db", "*.log"}, Exclude: []string{"logs"}}
+	if err := Write(&buffer, root, Zip, filter); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "app/config/app.yaml" || names[1] != "app/data/app.db" {
+		t.Fatalf("unexpected entries %v", names)
+	}
+}
+
+func TestTarGz(t *testing.T) {
+	root := tree(t)
+	var buffer bytes.Buffer
+	filter := &Filter{Skip: func(p string) bool { return path.Base(p) == "data" }}
+	if err := Write(&buffer, root, TarGz, filter); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			content, _ := io.ReadAll(tr)
+			files[header.Name] = string(content)
+		}
+	}
+	if len(files) != 3 || files["app/README.md"] != "readme" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	if _, ok := files["app/data/app.db"]; ok {
+		t.Fatal("skipped dir was archived")
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	if err := Write(io.Discard, t.TempDir(), "rar", nil); err == nil {
+		t.Fatal("expected an unsupported format error")
+	}
+	if err := Write(io.Discard, t.TempDir(), Zip, &Filter{Include: []string{"["}}); err == nil {
+		t.Fatal("expected an invalid glob error")
+	}
+}
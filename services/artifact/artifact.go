@@ -0,0 +1,301 @@
+package artifact
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/lib-files/fileutils"
+	"github.com/NubeIO/platform/services/installer"
+	"github.com/NubeIO/platform/utils/checksum"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultArtifact is used when the instance doesn't name its artifact
+const DefaultArtifact = "{name}-{version}-{arch}.zip"
+
+type Spec struct {
+	Name     string
+	Repo     string // base url, the artifact is fetched from <repo>/<version>/<artifact>
+	Version  string
+	Arch     string
+	Artifact string // file name, {name} {version} & {arch} get replaced
+	Checksum string // <algo>:<hex>, when empty <artifact url>.sha256 is fetched from the same server
+}
+
+func (s *Spec) FileName() string {
+	name := s.Artifact
+	if name == "" {
+		name = DefaultArtifact
+	}
+	return strings.NewReplacer("{name}", s.Name, "{version}", s.Version, "{arch}", s.Arch).Replace(name)
+}
+
+// Validate makes sure the name, version & file name can't point the download or install paths outside of their dirs
+func (s *Spec) Validate() error {
+	if err := installer.ValidatePathName("name", s.Name); err != nil {
+		return err
+	}
+	if err := installer.ValidatePathName("version", s.Version); err != nil {
+		return err
+	}
+	return installer.ValidatePathName("artifact", s.FileName())
+}
+
+func (s *Spec) URL() string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.Repo, "/"), s.Version, s.FileName())
+}
+
+type Fetcher struct {
+	Installer *installer.Installer
+	Client    *http.Client
+	mutex     sync.Mutex
+	locks     map[string]*sync.Mutex
+}
+
+func New(installer *installer.Installer) *Fetcher {
+	return &Fetcher{
+		Installer: installer,
+		Client:    &http.Client{Timeout: 10 * time.Minute},
+		locks:     make(map[string]*sync.Mutex),
+	}
+}
+
+// lock serialises the fetches of the same artifact, the others go on in parallel
+func (inst *Fetcher) lock(spec *Spec) func() {
+	inst.mutex.Lock()
+	key := inst.DownloadPath(spec)
+	lock, ok := inst.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		inst.locks[key] = lock
+	}
+	inst.mutex.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+func (inst *Fetcher) DownloadPath(spec *Spec) string {
+	return path.Join(inst.Installer.GetAppDownloadPathWithVersion(spec.Name, spec.Version), spec.FileName())
+}
+
+// ChecksumPath is the sha256sum file kept next to the download
+func (inst *Fetcher) ChecksumPath(spec *Spec) string {
+	return checksum.SidecarPath(inst.DownloadPath(spec))
+}
+
+func (inst *Fetcher) InstallPath(spec *Spec) string {
+	return inst.Installer.GetAppInstallPathWithVersion(spec.Name, spec.Version)
+}
+
+// Ensure makes sure the verified artifact is unpacked into the install path, it only hits the network when the cache is missing or corrupted
+func (inst *Fetcher) Ensure(spec *Spec) (string, error) {
+	if spec.Repo == "" || spec.Version == "" {
+		return "", errors.New("repo and version are required to fetch an artifact")
+	}
+	if err := spec.Validate(); err != nil {
+		return "", err
+	}
+	defer inst.lock(spec)()
+	expected, err := inst.expectedChecksum(spec, false)
+	if err != nil {
+		return "", err
+	}
+	downloadPath := inst.DownloadPath(spec)
+	refreshed := spec.Checksum != ""
+	err = checksum.Verify(downloadPath, expected)
+	if err != nil && !os.IsNotExist(err) && !refreshed {
+		// the cached sidecar is stale when the artifact got published again under the same version
+		if expected, err = inst.expectedChecksum(spec, true); err != nil {
+			return "", err
+		}
+		refreshed = true
+		err = checksum.Verify(downloadPath, expected)
+	}
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("cached artifact %s is invalid, fetching it again: %s", downloadPath, err.Error())
+		}
+		if err = inst.download(spec, downloadPath); err != nil {
+			return "", err
+		}
+		err = checksum.Verify(downloadPath, expected)
+		if err != nil && !refreshed {
+			if expected, err = inst.expectedChecksum(spec, true); err == nil {
+				err = checksum.Verify(downloadPath, expected)
+			}
+		}
+		if err != nil {
+			_ = os.Remove(downloadPath)
+			return "", err
+		}
+		_ = os.RemoveAll(inst.InstallPath(spec)) // the unpacked files belong to the old download
+	}
+	installPath := inst.InstallPath(spec)
+	if fileutils.DirExists(installPath) {
+		return installPath, nil
+	}
+	if err = inst.unpack(downloadPath, installPath); err != nil {
+		return "", err
+	}
+	return installPath, nil
+}
+
+// expectedChecksum prefers the instance checksum and falls back to the .sha256 file next to the artifact, which is
+// cached until refresh; it comes from the same server so it only catches a broken download, pin the checksum to catch a
+// tampered one
+func (inst *Fetcher) expectedChecksum(spec *Spec, refresh bool) (string, error) {
+	if spec.Checksum != "" {
+		if _, _, err := checksum.Parse(spec.Checksum); err != nil {
+			return "", err
+		}
+		return spec.Checksum, nil
+	}
+	sidecar := inst.ChecksumPath(spec)
+	data, err := os.ReadFile(sidecar)
+	if err != nil || refresh {
+		url := spec.URL() + ".sha256"
+		log.Warnf("no checksum pinned for %s %s, using %s from the same server", spec.Name, spec.Version, url)
+		data, err = inst.get(url)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("no checksum set and failed to fetch one: %s", err.Error()))
+		}
+		if err = os.MkdirAll(path.Dir(sidecar), os.FileMode(inst.Installer.FileMode)); err != nil {
+			return "", err
+		}
+		if err = os.WriteFile(sidecar, data, 0644); err != nil {
+			return "", err
+		}
+	}
+	// sha256sum format: <hex>  <file name>
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", errors.New(fmt.Sprintf("empty checksum file %s", sidecar))
+	}
+	return fmt.Sprintf("%s:%s", checksum.SHA256, fields[0]), nil
+}
+
+func (inst *Fetcher) get(url string) ([]byte, error) {
+	resp, err := inst.Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("GET %s: %s", url, resp.Status))
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+}
+
+// download writes into a temp file first so an interrupted download never looks like a cached one
+func (inst *Fetcher) download(spec *Spec, downloadPath string) error {
+	url := spec.URL()
+	log.Infof("downloading artifact %s", url)
+	if err := os.MkdirAll(path.Dir(downloadPath), os.FileMode(inst.Installer.FileMode)); err != nil {
+		return err
+	}
+	resp, err := inst.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("GET %s: %s", url, resp.Status))
+	}
+	tmp, err := os.CreateTemp(path.Dir(downloadPath), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, resp.Body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), downloadPath)
+}
+
+// unpack extracts zip & tar.gz archives, anything else is treated as a single binary
+func (inst *Fetcher) unpack(source, installPath string) error {
+	mode := os.FileMode(inst.Installer.FileMode)
+	tmpDir := installPath + ".tmp"
+	_ = os.RemoveAll(tmpDir)
+	if err := os.MkdirAll(tmpDir, mode); err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	var err error
+	switch {
+	case strings.HasSuffix(source, ".zip"):
+		_, err = fileutils.Unzip(source, tmpDir, mode)
+	case strings.HasSuffix(source, ".tar.gz"), strings.HasSuffix(source, ".tgz"):
+		err = untar(source, tmpDir, mode)
+	default:
+		destination := path.Join(tmpDir, path.Base(source))
+		if err = fileutils.CopyFile(source, destination); err == nil {
+			err = os.Chmod(destination, mode)
+		}
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to unpack %s: %s", source, err.Error()))
+	}
+	return os.Rename(tmpDir, installPath)
+}
+
+func untar(source, destination string, mode os.FileMode) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, header.Name)
+		if !strings.HasPrefix(target, filepath.Clean(destination)+string(os.PathSeparator)) {
+			return errors.New(fmt.Sprintf("%s: illegal file path", header.Name))
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(target), mode); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&os.ModePerm)
+			if err != nil {
+				return err
+			}
+			if _, err = io.Copy(out, tr); err != nil {
+				_ = out.Close()
+				return err
+			}
+			if err = out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
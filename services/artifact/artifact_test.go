@@ -0,0 +1,129 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/NubeIO/platform/services/installer"
+	"github.com/NubeIO/platform/services/rubixregistry"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func buildZip(t *testing.T) []byte {
+	return buildZipWith(t, "#!/bin/sh\necho hello\n")
+}
+
+func buildZipWith(t *testing.T, script string) []byte {
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	f, err := w.Create("app/run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte(script))
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestEnsure(t *testing.T) {
+	data := buildZip(t)
+	sum := sha256.Sum256(data)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/v1.0.0/demo-v1.0.0-amd64.zip":
+			_, _ = w.Write(data)
+		case "/v1.0.0/demo-v1.0.0-amd64.zip.sha256":
+			_, _ = fmt.Fprintf(w, "%s  demo-v1.0.0-amd64.zip\n", hex.EncodeToString(sum[:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := New(installer.New(&installer.Installer{}, rubixregistry.New(t.TempDir())))
+	spec := &Spec{Name: "demo", Repo: server.URL, Version: "v1.0.0", Arch: "amd64"}
+	installPath, err := fetcher.Ensure(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path.Join(installPath, "app", "run.sh")); err != nil {
+		t.Fatal(err)
+	}
+
+	requests = 0
+	if _, err = fetcher.Ensure(spec); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 {
+		t.Fatalf("expected the cached artifact to be used, got %d requests", requests)
+	}
+
+	spec.Checksum = "sha256:" + hex.EncodeToString(make([]byte, 32))
+	if _, err = fetcher.Ensure(spec); err == nil {
+		t.Fatal("expected a checksum mismatch")
+	}
+
+	for _, bad := range []*Spec{
+		{Name: "../..", Repo: server.URL, Version: "v1.0.0", Arch: "amd64"},
+		{Name: "demo", Repo: server.URL, Version: "..", Arch: "amd64"},
+		{Name: "demo", Repo: server.URL, Version: "v1.0.0", Artifact: "../../{name}.zip"},
+	} {
+		if _, err = fetcher.Ensure(bad); err == nil {
+			t.Fatalf("expected %+v to be refused", bad)
+		}
+	}
+}
+
+func TestEnsureRefetchesStaleSidecar(t *testing.T) {
+	data := buildZip(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256(data)
+		switch r.URL.Path {
+		case "/v1.0.0/demo-v1.0.0-amd64.zip":
+			_, _ = w.Write(data)
+		case "/v1.0.0/demo-v1.0.0-amd64.zip.sha256":
+			_, _ = fmt.Fprintf(w, "%s  demo-v1.0.0-amd64.zip\n", hex.EncodeToString(sum[:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := New(installer.New(&installer.Installer{}, rubixregistry.New(t.TempDir())))
+	spec := &Spec{Name: "demo", Repo: server.URL, Version: "v1.0.0", Arch: "amd64"}
+	if _, err := fetcher.Ensure(spec); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same version got published again, the cached artifact and sidecar are both stale
+	data = buildZipWith(t, "#!/bin/sh\necho republished\n")
+	_ = os.WriteFile(fetcher.DownloadPath(spec), []byte("corrupted"), 0644)
+	installPath, err := fetcher.Ensure(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := os.ReadFile(path.Join(installPath, "app", "run.sh"))
+	if err != nil || !bytes.Contains(script, []byte("republished")) {
+		t.Fatalf("expected the republished artifact, got %q, %v", script, err)
+	}
+
+	// the cached sidecar is stale and there's no cached artifact
+	data = buildZipWith(t, "#!/bin/sh\necho again\n")
+	_ = os.Remove(fetcher.DownloadPath(spec))
+	if installPath, err = fetcher.Ensure(spec); err != nil {
+		t.Fatal(err)
+	}
+	if script, err = os.ReadFile(path.Join(installPath, "app", "run.sh")); err != nil || !bytes.Contains(script, []byte("again")) {
+		t.Fatalf("expected the artifact published again, got %q, %v", script, err)
+	}
+}
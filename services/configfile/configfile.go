@@ -0,0 +1,294 @@
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	JSON = "json"
+	YAML = "yaml"
+	Env  = "env"
+)
+
+// lock serialises the read, patch & write cycles so two patches can't lose each other's changes
+var lock sync.Mutex
+
+// Format is picked from the file name: *.json, *.yml/*.yaml and .env/*.env
+func Format(filePath string) (string, error) {
+	name := filepath.Base(filePath)
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case ext == ".json":
+		return JSON, nil
+	case ext == ".yml" || ext == ".yaml":
+		return YAML, nil
+	case ext == ".env" || name == ".env" || strings.HasPrefix(name, ".env."):
+		return Env, nil
+	}
+	return "", errors.New(fmt.Sprintf("unsupported config file %s, it needs to be json, yaml or .env", name))
+}
+
+// Read returns the config as json, the keys stay in the order of the file
+func Read(filePath string) (json.RawMessage, string, error) {
+	format, err := Format(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	if format == Env {
+		out, err := parseEnv(data).toJSON()
+		return out, format, err
+	}
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, "", errors.New(fmt.Sprintf("failed to parse %s: %s", filePath, err.Error()))
+	}
+	out, err := toJSON(doc)
+	return out, format, err
+}
+
+// Patch applies an RFC 7386 merge-patch and writes the file back in its own format
+func Patch(filePath string, patch []byte) (json.RawMessage, error) {
+	format, err := Format(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(patch) {
+		return nil, errors.New("the merge-patch needs to be valid json")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var updated []byte
+	if format == Env {
+		env := parseEnv(data)
+		if err = env.merge(patch); err != nil {
+			return nil, err
+		}
+		updated = env.bytes()
+	} else {
+		doc, err := parseDocument(data)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("failed to parse %s: %s", filePath, err.Error()))
+		}
+		patchDoc, err := parseDocument(patch)
+		if err != nil {
+			return nil, err
+		}
+		doc = mergePatch(doc, patchDoc)
+		if format == JSON {
+			updated, err = encodeJSON(doc, indentOf(data))
+		} else {
+			updated, err = encodeYAML(doc, indentOf(data))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = writeFile(filePath, updated); err != nil {
+		return nil, err
+	}
+	out, _, err := Read(filePath)
+	return out, err
+}
+
+// writeFile replaces the file through a rename so a reader never sees half of it, the mode is kept
+func writeFile(filePath string, data []byte) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// indentOf guesses the indent from the first indented line, 2 when there is none
+func indentOf(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == line || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		return len(line) - len(trimmed)
+	}
+	return 2
+}
+
+func parseDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 { // empty file or only comments
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return doc.Content[0], nil
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// mergePatch follows RFC 7386: objects are merged key by key, null removes a key and anything else replaces the target
+func mergePatch(target, patch *yaml.Node) *yaml.Node {
+	if patch.Kind != yaml.MappingNode {
+		return plain(patch)
+	}
+	target = resolveAlias(target)
+	if target.Kind != yaml.MappingNode {
+		target = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		key, value := patch.Content[i].Value, patch.Content[i+1]
+		index := -1
+		for j := 0; j+1 < len(target.Content); j += 2 {
+			if target.Content[j].Value == key {
+				index = j
+				break
+			}
+		}
+		if isNull(value) {
+			if index >= 0 {
+				target.Content = append(target.Content[:index], target.Content[index+2:]...)
+			}
+			continue
+		}
+		if index < 0 {
+			target.Content = append(target.Content, plain(patch.Content[i]), mergePatch(&yaml.Node{}, value))
+			continue
+		}
+		old := target.Content[index+1]
+		merged := mergePatch(old, value)
+		if merged != old {
+			// the comments belong to the setting, not to its old value
+			merged.HeadComment, merged.LineComment, merged.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		}
+		target.Content[index+1] = merged
+	}
+	return target
+}
+
+// plain drops the json styles of patch nodes so they are written as block yaml, quoted strings get quoted again by the encoder when needed
+func plain(node *yaml.Node) *yaml.Node {
+	node.Style = 0
+	for _, child := range node.Content {
+		plain(child)
+	}
+	return node
+}
+
+func toJSON(node *yaml.Node) (json.RawMessage, error) {
+	var buffer bytes.Buffer
+	if err := writeJSON(&buffer, node); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func encodeJSON(node *yaml.Node, indent int) ([]byte, error) {
+	compact, err := toJSON(node)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err = json.Indent(&buffer, compact, "", strings.Repeat(" ", indent)); err != nil {
+		return nil, err
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), nil
+}
+
+func encodeYAML(node *yaml.Node, indent int) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// writeJSON walks the nodes instead of decoding into a map, so the key order of the file is kept
+func writeJSON(buffer *bytes.Buffer, node *yaml.Node) error {
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		buffer.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			buffer.Write(key)
+			buffer.WriteByte(':')
+			if err := writeJSON(buffer, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case yaml.SequenceNode:
+		buffer.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := writeJSON(buffer, child); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		// numbers are copied as written, decoding would turn 1.0 into 1
+		if (node.Tag == "!!int" || node.Tag == "!!float") && json.Valid([]byte(node.Value)) {
+			buffer.WriteString(node.Value)
+			return nil
+		}
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		out, err := json.Marshal(value)
+		if err != nil {
+			return errors.New(fmt.Sprintf("line %d: %s", node.Line, err.Error()))
+		}
+		buffer.Write(out)
+	}
+	return nil
+}
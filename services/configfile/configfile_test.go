@@ -0,0 +1,92 @@
+package configfile
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func patchFile(t *testing.T, name, content, patch string) (string, string) {
+	filePath := path.Join(t.TempDir(), name)
+	if err := os.WriteFile(filePath, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Patch(filePath, []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), string(data)
+}
+
+func TestPatchYAML(t *testing.T) {
+	content := `# server settings
+server:
+  port: 1660 # http
+  host: 0.0.0.0
+debug: true
+tags:
+  - a
+`
+	out, data := patchFile(t, "config.yml", content, `{"server":{"port":1661,"tls":{"enabled":true,"key":null}},"debug":null,"name":"true"}`)
+	expected := `# server settings
+server:
+  port: 1661 # http
+  host: 0.0.0.0
+  tls:
+    enabled: true
+tags:
+  - a
+name: "true"
+`
+	if data != expected {
+		t.Fatalf("unexpected yaml:\n%s", data)
+	}
+	if out != `{"server":{"port":1661,"host":"0.0.0.0","tls":{"enabled":true}},"tags":["a"],"name":"true"}` {
+		t.Fatalf("unexpected json %s", out)
+	}
+}
+
+func TestPatchJSON(t *testing.T) {
+	content := "{\n    \"b\": 1.0,\n    \"a\": {\"x\": [1, 2]}\n}\n"
+	_, data := patchFile(t, "config.json", content, `{"a":{"x":[3],"y":"z"},"c":false}`)
+	expected := "{\n    \"b\": 1.0,\n    \"a\": {\n        \"x\": [\n            3\n        ],\n        \"y\": \"z\"\n    },\n    \"c\": false\n}\n"
+	if data != expected {
+		t.Fatalf("unexpected json:\n%s", data)
+	}
+}
+
+func TestPatchEnv(t *testing.T) {
+	content := "# app\nPORT=1660\nexport NAME='my app'\nDEBUG=true\n"
+	out, data := patchFile(t, ".env", content, `{"PORT":1661,"DEBUG":null,"TOKEN":"a b"}`)
+	if data != "# app\nPORT=1661\nexport NAME='my app'\nTOKEN=\"a b\"\n" {
+		t.Fatalf("unexpected env:\n%s", data)
+	}
+	if out != `{"PORT":"1661","NAME":"my app","TOKEN":"a b"}` {
+		t.Fatalf("unexpected json %s", out)
+	}
+	filePath := path.Join(t.TempDir(), "app.env")
+	_ = os.WriteFile(filePath, []byte("A=1\n"), 0640)
+	if _, err := Patch(filePath, []byte(`{"A":{"B":1}}`)); err == nil {
+		t.Fatal("expected nested values to be rejected")
+	}
+}
+
+func TestPatchEnvDuplicates(t *testing.T) {
+	content := "A=1\nB=x\nA=2\n"
+	out, data := patchFile(t, ".env", content, `{}`)
+	if data != content || out != `{"A":"2","B":"x"}` {
+		t.Fatalf("expected the last value to be read, got %s from:\n%s", out, data)
+	}
+	out, data = patchFile(t, ".env", content, `{"A":3}`)
+	if data != "B=x\nA=3\n" || out != `{"B":"x","A":"3"}` {
+		t.Fatalf("expected the duplicates to be dropped, got %s from:\n%s", out, data)
+	}
+	_, data = patchFile(t, ".env", content, `{"A":null}`)
+	if data != "B=x\n" {
+		t.Fatalf("expected every line of the key to be removed:\n%s", data)
+	}
+}
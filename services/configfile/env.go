@@ -0,0 +1,159 @@
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+)
+
+type envLine struct {
+	raw    string // comments & blank lines are written back as they were
+	key    string
+	value  string
+	export bool
+}
+
+type envFile struct {
+	lines []*envLine
+}
+
+func parseEnv(data []byte) *envFile {
+	env := &envFile{}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return env
+	}
+	for _, raw := range strings.Split(text, "\n") {
+		line := &envLine{raw: raw}
+		trimmed := strings.TrimSpace(raw)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			if strings.HasPrefix(trimmed, "export ") {
+				line.export = true
+				trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "export "))
+			}
+			if key, value, ok := strings.Cut(trimmed, "="); ok {
+				line.key = strings.TrimSpace(key)
+				line.value = unquote(strings.TrimSpace(value))
+			}
+		}
+		env.lines = append(env.lines, line)
+	}
+	return env
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 { // inline comment
+		return strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"'#$\\\n`") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// find returns the last line of the key, shells and systemd use the last one when a key is set twice
+func (e *envFile) find(key string) int {
+	for i := len(e.lines) - 1; i >= 0; i-- {
+		if e.lines[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// dropDuplicates removes the lines of the key before its last one
+func (e *envFile) dropDuplicates(key string) {
+	last := e.find(key)
+	lines := e.lines[:0]
+	for i, line := range e.lines {
+		if line.key != key || i == last {
+			lines = append(lines, line)
+		}
+	}
+	e.lines = lines
+}
+
+func (e *envFile) toJSON() (json.RawMessage, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	seen := map[string]bool{}
+	for _, line := range e.lines {
+		if line.key == "" || seen[line.key] {
+			continue
+		}
+		seen[line.key] = true
+		if len(seen) > 1 {
+			buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(line.key)
+		value, _ := json.Marshal(e.lines[e.find(line.key)].value)
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// merge takes a flat merge-patch, .env has no nesting so the values need to be strings, numbers, bools or null
+func (e *envFile) merge(patch []byte) error {
+	doc, err := parseDocument(patch)
+	if err != nil {
+		return err
+	}
+	if doc.Kind != yaml.MappingNode {
+		return errors.New("the merge-patch of a .env file needs to be an object")
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i].Value, doc.Content[i+1]
+		if isNull(value) {
+			for index := e.find(key); index >= 0; index = e.find(key) {
+				e.lines = append(e.lines[:index], e.lines[index+1:]...)
+			}
+			continue
+		}
+		if value.Kind != yaml.ScalarNode {
+			return errors.New(fmt.Sprintf("%s: a .env value can't be an object or a list", key))
+		}
+		e.dropDuplicates(key)
+		if index := e.find(key); index >= 0 {
+			e.lines[index].value = value.Value
+			e.lines[index].raw = ""
+		} else {
+			e.lines = append(e.lines, &envLine{key: key, value: value.Value})
+		}
+	}
+	return nil
+}
+
+func (e *envFile) bytes() []byte {
+	var buffer bytes.Buffer
+	for _, line := range e.lines {
+		switch {
+		case line.key == "" || line.raw != "":
+			buffer.WriteString(line.raw)
+		case line.export:
+			buffer.WriteString(fmt.Sprintf("export %s=%s", line.key, quote(line.value)))
+		default:
+			buffer.WriteString(fmt.Sprintf("%s=%s", line.key, quote(line.value)))
+		}
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,100 @@
+package fieldpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+type Change struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Diff compares two pointers to the same struct field by field, empty and nil values are the same.
+// The fields are named by their json tag
+func Diff(old, new interface{}) []*Change {
+	changes := make([]*Change, 0)
+	oldValue := reflect.ValueOf(old).Elem()
+	newValue := reflect.ValueOf(new).Elem()
+	for i := 0; i < oldValue.NumField(); i++ {
+		o := oldValue.Field(i)
+		n := newValue.Field(i)
+		if (o.IsZero() || isEmpty(o)) && (n.IsZero() || isEmpty(n)) {
+			continue
+		}
+		if reflect.DeepEqual(o.Interface(), n.Interface()) {
+			continue
+		}
+		changes = append(changes, &Change{
+			Field: jsonFieldName(oldValue.Type().Field(i)),
+			Old:   o.Interface(),
+			New:   n.Interface(),
+		})
+	}
+	return changes
+}
+
+// Has is true when one of the fields changed
+func Has(changes []*Change, fields ...string) bool {
+	for _, change := range changes {
+		for _, field := range fields {
+			if change.Field == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Merge applies an RFC 7386 merge-patch to base and decodes the result into out: objects are merged key by key,
+// lists & values are replaced and null removes the key
+func Merge(base interface{}, patch []byte, out interface{}) error {
+	data, err := json.Marshal(base)
+	if err != nil {
+		return err
+	}
+	var target, changes interface{}
+	if err = json.Unmarshal(data, &target); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(patch, &changes); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(mergePatch(target, changes)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]interface{})
+	if !ok {
+		merged = map[string]interface{}{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = mergePatch(merged[key], value)
+		}
+	}
+	return merged
+}
+
+func isEmpty(v reflect.Value) bool {
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
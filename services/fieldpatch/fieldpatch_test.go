@@ -0,0 +1,103 @@
+package fieldpatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+type limits struct {
+	Nice         int    `json:"nice,omitempty"`
+	MaxOpenFiles uint64 `json:"maxOpenFiles,omitempty"`
+}
+
+type host struct {
+	Name    string            `json:"name"`
+	Port    int               `json:"port,omitempty"`
+	Env     []string          `json:"environmentVars,omitempty"`
+	Secrets map[string]string `json:"secrets,omitempty"`
+	Limits  *limits           `json:"limits,omitempty"`
+	Comment string
+}
+
+func fields(changes []*Change) []string {
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		names = append(names, change.Field)
+	}
+	return names
+}
+
+func TestDiff(t *testing.T) {
+	base := host{Name: "demo", Port: 1660, Env: []string{"A=1"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 5}}
+	tests := []struct {
+		name    string
+		old     host
+		new     host
+		changes []string
+	}{
+		{"same", base, base, []string{}},
+		{"nil and empty are the same", host{Name: "demo"}, host{Name: "demo", Env: []string{}, Secrets: map[string]string{}}, []string{}},
+		{"value", base, host{Name: "demo", Port: 1661, Env: []string{"A=1"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 5}}, []string{"port"}},
+		{"list", base, host{Name: "demo", Port: 1660, Env: []string{"A=2"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 5}}, []string{"environmentVars"}},
+		{"pointer", base, host{Name: "demo", Port: 1660, Env: []string{"A=1"}, Secrets: map[string]string{"K": "v"}}, []string{"limits"}},
+		{"untagged field", host{}, host{Comment: "x"}, []string{"Comment"}},
+		{"several", base, host{Name: "other"}, []string{"name", "port", "environmentVars", "secrets", "limits"}},
+	}
+	for _, test := range tests {
+		old, updated := test.old, test.new
+		if got := fields(Diff(&old, &updated)); !reflect.DeepEqual(got, test.changes) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.changes, got)
+		}
+	}
+	changes := Diff(&host{Port: 1}, &host{Port: 2})
+	if changes[0].Old != 1 || changes[0].New != 2 {
+		t.Fatalf("unexpected change %+v", changes[0])
+	}
+	if !Has(changes, "name", "port") || Has(changes, "name") {
+		t.Fatal("unexpected Has")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := &host{Name: "demo", Port: 1660, Env: []string{"A=1", "B=2"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 5, MaxOpenFiles: 1024}}
+	tests := []struct {
+		name     string
+		patch    string
+		expected host
+	}{
+		{"empty patch", `{}`,
+			host{Name: "demo", Port: 1660, Env: []string{"A=1", "B=2"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 5, MaxOpenFiles: 1024}}},
+		{"value", `{"port":1661}`,
+			host{Name: "demo", Port: 1661, Env: []string{"A=1", "B=2"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 5, MaxOpenFiles: 1024}}},
+		{"lists are replaced", `{"environmentVars":["C=3"]}`,
+			host{Name: "demo", Port: 1660, Env: []string{"C=3"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 5, MaxOpenFiles: 1024}}},
+		{"maps are merged", `{"secrets":{"L":"w"}}`,
+			host{Name: "demo", Port: 1660, Env: []string{"A=1", "B=2"}, Secrets: map[string]string{"K": "v", "L": "w"}, Limits: &limits{Nice: 5, MaxOpenFiles: 1024}}},
+		{"objects are merged", `{"limits":{"nice":10}}`,
+			host{Name: "demo", Port: 1660, Env: []string{"A=1", "B=2"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{Nice: 10, MaxOpenFiles: 1024}}},
+		{"null clears", `{"limits":null,"environmentVars":null}`,
+			host{Name: "demo", Port: 1660, Secrets: map[string]string{"K": "v"}}},
+		{"null deletes a map key", `{"secrets":{"K":null,"L":"w"}}`,
+			host{Name: "demo", Port: 1660, Env: []string{"A=1", "B=2"}, Secrets: map[string]string{"L": "w"}, Limits: &limits{Nice: 5, MaxOpenFiles: 1024}}},
+		{"null deletes a nested field", `{"limits":{"nice":null}}`,
+			host{Name: "demo", Port: 1660, Env: []string{"A=1", "B=2"}, Secrets: map[string]string{"K": "v"}, Limits: &limits{MaxOpenFiles: 1024}}},
+	}
+	for _, test := range tests {
+		patched := &host{}
+		if err := Merge(base, []byte(test.patch), patched); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if !reflect.DeepEqual(*patched, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, *patched)
+		}
+	}
+	if base.Port != 1660 || len(base.Secrets) != 1 || base.Limits.Nice != 5 {
+		t.Fatalf("the base must not be changed, got %+v", base)
+	}
+	if err := Merge(base, []byte(`{"port":"x"}`), &host{}); err == nil {
+		t.Fatal("expected a type error")
+	}
+	if err := Merge(base, []byte(`{"port":`), &host{}); err == nil {
+		t.Fatal("expected a json error")
+	}
+}
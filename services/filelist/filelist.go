@@ -0,0 +1,281 @@
+package filelist
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	SortName     = "name"
+	SortPath     = "path"
+	SortSize     = "size"
+	SortModified = "modified"
+
+	TypeFile = "file"
+	TypeDir  = "dir"
+)
+
+type Entry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	IsDir      bool      `json:"is_file"` // the key the existing clients read, it is true for dirs
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	Owner      string    `json:"owner"`
+	Group      string    `json:"group"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	Symlink    bool      `json:"symlink"`
+	LinkTarget string    `json:"linkTarget,omitempty"`
+}
+
+type Options struct {
+	MaxDepth int    // walk only, 0 is unlimited
+	Glob     string // matched against the name
+	Regex    string // matched against the path relative to the root
+	Type     string // file or dir, empty for both
+	Sort     string // name, path, size or modified; the walk order when empty
+	Desc     bool
+	Offset   int
+	Limit    int                 // 0 is unlimited
+	Skip     func(p string) bool // skipped dirs aren't walked into
+}
+
+func (o *Options) validate() (*regexp.Regexp, error) {
+	if o.Glob != "" {
+		if _, err := path.Match(o.Glob, ""); err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid glob %s", o.Glob))
+		}
+	}
+	switch o.Sort {
+	case "", SortName, SortPath, SortSize, SortModified:
+	default:
+		return nil, errors.New(fmt.Sprintf("sort must be one of %s, %s, %s or %s", SortName, SortPath, SortSize, SortModified))
+	}
+	switch o.Type {
+	case "", TypeFile, TypeDir:
+	default:
+		return nil, errors.New(fmt.Sprintf("type must be %s or %s", TypeFile, TypeDir))
+	}
+	if o.MaxDepth < 0 || o.Offset < 0 || o.Limit < 0 {
+		return nil, errors.New("max_depth, offset and limit can not be negative")
+	}
+	if o.Regex == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(o.Regex)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid regex %s: %s", o.Regex, err.Error()))
+	}
+	return re, nil
+}
+
+// List returns the dir content, total is the number of matches before the offset & limit
+func List(dir string, o *Options) ([]*Entry, int, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !info.IsDir() {
+		return nil, 0, errors.New("it needs to be a directory, found a file")
+	}
+	listing := *o
+	listing.MaxDepth = 1
+	return collect(dir, &listing, false)
+}
+
+// Walk returns the tree under root, root included, in the order of filepath.WalkDir unless sorted
+func Walk(root string, o *Options) ([]*Entry, int, error) {
+	return collect(root, o, true)
+}
+
+// Each calls fn for the entries matching the filters in the walk order, the offset, limit & sort are ignored
+func Each(root string, o *Options, fn func(p string, d fs.DirEntry) error) error {
+	return each(root, o, true, fn)
+}
+
+func each(root string, o *Options, includeRoot bool, fn func(p string, d fs.DirEntry) error) error {
+	re, err := o.validate()
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		depth := 0
+		if rel != "." {
+			depth = strings.Count(filepath.ToSlash(rel), "/") + 1
+		}
+		if depth > 0 && o.Skip != nil && o.Skip(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if (depth > 0 || includeRoot) && matches(d, rel, o, re) {
+			if err = fn(p, d); err != nil {
+				return err
+			}
+		}
+		if d.IsDir() && depth > 0 && o.MaxDepth > 0 && depth >= o.MaxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+func collect(root string, o *Options, includeRoot bool) ([]*Entry, int, error) {
+	owners := newOwners()
+	// without sorting only the page needs to be stat-ed, the rest is only counted
+	paged := o.Sort == ""
+	entries := make([]*Entry, 0)
+	total := 0
+	err := each(root, o, includeRoot, func(p string, d fs.DirEntry) error {
+		return add(&entries, &total, p, d, o, paged, owners)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if paged {
+		return entries, total, nil
+	}
+	sortEntries(entries, o.Sort, o.Desc)
+	return page(entries, o.Offset, o.Limit), total, nil
+}
+
+func matches(d fs.DirEntry, rel string, o *Options, re *regexp.Regexp) bool {
+	if o.Type == TypeFile && d.IsDir() || o.Type == TypeDir && !d.IsDir() {
+		return false
+	}
+	if o.Glob != "" {
+		if ok, _ := path.Match(o.Glob, d.Name()); !ok {
+			return false
+		}
+	}
+	return re == nil || re.MatchString(filepath.ToSlash(rel))
+}
+
+func add(entries *[]*Entry, total *int, p string, d fs.DirEntry, o *Options, paged bool, owners *owners) error {
+	index := *total
+	*total++
+	if paged && (index < o.Offset || o.Limit > 0 && index >= o.Offset+o.Limit) {
+		return nil
+	}
+	entry, err := newEntry(p, d, owners)
+	if err != nil {
+		return err
+	}
+	*entries = append(*entries, entry)
+	return nil
+}
+
+func newEntry(p string, d fs.DirEntry, owners *owners) (*Entry, error) {
+	info, err := d.Info()
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{
+		Name:       d.Name(),
+		Path:       p,
+		IsDir:      info.IsDir(),
+		Size:       info.Size(),
+		Mode:       info.Mode().String(),
+		ModifiedAt: info.ModTime(),
+		Symlink:    info.Mode()&fs.ModeSymlink != 0,
+	}
+	if entry.Symlink {
+		entry.LinkTarget, _ = os.Readlink(p)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		entry.Owner = owners.user(stat.Uid)
+		entry.Group = owners.group(stat.Gid)
+	}
+	return entry, nil
+}
+
+func sortEntries(entries []*Entry, by string, desc bool) {
+	less := func(a, b *Entry) bool {
+		switch by {
+		case SortPath:
+			return a.Path < b.Path
+		case SortSize:
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case SortModified:
+			if !a.ModifiedAt.Equal(b.ModifiedAt) {
+				return a.ModifiedAt.Before(b.ModifiedAt)
+			}
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Path < b.Path
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if desc {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
+
+func page(entries []*Entry, offset, limit int) []*Entry {
+	if offset >= len(entries) {
+		return make([]*Entry, 0)
+	}
+	entries = entries[offset:]
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// owners caches the uid & gid lookups, a walk hits the same few ids over and over
+type owners struct {
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+func newOwners() *owners {
+	return &owners{users: map[uint32]string{}, groups: map[uint32]string{}}
+}
+
+func (o *owners) user(uid uint32) string {
+	name, ok := o.users[uid]
+	if !ok {
+		name = strconv.Itoa(int(uid))
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		o.users[uid] = name
+	}
+	return name
+}
+
+func (o *owners) group(gid uint32) string {
+	name, ok := o.groups[gid]
+	if !ok {
+		name = strconv.Itoa(int(gid))
+		if g, err := user.LookupGroupId(name); err == nil {
+			name = g.Name
+		}
+		o.groups[gid] = name
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+package filelist
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func tree(t *testing.T) string {
+	root := t.TempDir()
+	for name, size := range map[string]int{"a.log": 3, "b.db": 10, "sub/c.log": 1, "sub/deep/d.log": 5} {
+		p := path.Join(root, name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestList(t *testing.T) {
+	root := tree(t)
+	entries, total, err := List(root, &Options{Sort: SortName, Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || entries[0].Name != "sub" || !entries[0].IsDir {
+		t.Fatalf("unexpected listing %d %+v", total, entries)
+	}
+	entries, total, err = List(root, &Options{Type: TypeFile, Sort: SortSize, Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || entries[0].Name != "b.db" {
+		t.Fatalf("unexpected listing %d %+v", total, entries)
+	}
+	if entries[0].Size != 10 || entries[0].Owner == "" {
+		t.Fatalf("missing metadata %+v", entries[0])
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := tree(t)
+	entries, total, err := Walk(root, &Options{Glob: "*.log", Offset: 1, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(entries) != 1 || entries[0].Name != "c.log" {
+		t.Fatalf("unexpected page %d %+v", total, entries)
+	}
+	entries, total, err = Walk(root, &Options{MaxDepth: 1, Type: TypeFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("expected the files of the first level only, got %+v", entries)
+	}
+	if _, _, err = Walk(root, &Options{Regex: "("}); err == nil {
+		t.Fatal("expected an invalid regex error")
+	}
+}
@@ -0,0 +1,160 @@
+package fswatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	Create = "create"
+	Write  = "write"
+	Remove = "remove"
+	Rename = "rename"
+)
+
+// MaxDirs caps the inotify watches of one subscription, the kernel default is 8192 watches per user
+var MaxDirs = 1024
+
+type Event struct {
+	Path  string    `json:"path"`
+	Op    string    `json:"op"`
+	IsDir bool      `json:"isDir"`
+	Time  time.Time `json:"time"`
+}
+
+type Options struct {
+	Recursive bool
+	Skip      func(p string) bool // skipped paths get no events and their dirs aren't watched
+	Ready     func()              // called once the watches are set up, changes made after it aren't missed
+}
+
+type watch struct {
+	watcher *fsnotify.Watcher
+	options *Options
+	dirs    map[string]bool
+	last    *Event
+}
+
+// Watch calls fn for the changes under root until ctx is done or fn fails, new dirs get watched too when recursive
+func Watch(ctx context.Context, root string, o *Options, fn func(e *Event) error) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	w := &watch{watcher: watcher, options: o, dirs: map[string]bool{}}
+	if !info.IsDir() || !o.Recursive {
+		err = w.add(root)
+	} else {
+		err = w.addTree(root)
+	}
+	if err != nil {
+		return err
+	}
+	if o.Ready != nil {
+		o.Ready()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-watcher.Errors:
+			return err
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if e := w.event(event); e != nil {
+				if err := fn(e); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *watch) skip(p string) bool {
+	return w.options.Skip != nil && w.options.Skip(p)
+}
+
+func (w *watch) add(p string) error {
+	if w.dirs[p] {
+		return nil
+	}
+	if len(w.dirs) >= MaxDirs {
+		return errors.New(fmt.Sprintf("more than %d dirs to watch, pick a smaller tree or watch it without recursive", MaxDirs))
+	}
+	if err := w.watcher.Add(p); err != nil {
+		return errors.New(fmt.Sprintf("failed to watch %s: %s", p, err.Error()))
+	}
+	w.dirs[p] = true
+	return nil
+}
+
+func (w *watch) addTree(root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil // a dir which went away or which we can't read
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && w.skip(p) {
+			return filepath.SkipDir
+		}
+		return w.add(p)
+	})
+}
+
+func (w *watch) event(event fsnotify.Event) *Event {
+	if w.skip(event.Name) {
+		return nil
+	}
+	e := &Event{Path: event.Name, Time: time.Now().UTC()}
+	switch {
+	case event.Op&fsnotify.Create != 0:
+		e.Op = Create
+	case event.Op&fsnotify.Write != 0:
+		e.Op = Write
+	case event.Op&fsnotify.Remove != 0:
+		e.Op = Remove
+	case event.Op&fsnotify.Rename != 0:
+		e.Op = Rename
+	default:
+		return nil // chmod
+	}
+	if e.Op == Remove || e.Op == Rename {
+		// a watched dir reports its own removal besides the one from its parent, only the first is sent
+		if w.last != nil && w.last.Op == e.Op && w.last.Path == e.Path {
+			return nil
+		}
+		if w.dirs[e.Path] {
+			e.IsDir = true
+			delete(w.dirs, e.Path)
+		}
+	}
+	w.last = e
+	if e.Op == Create {
+		if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+			e.IsDir = true
+			if w.options.Recursive {
+				// files created in the new dir before the watch is added are missed, the client gets the dir event and can list it
+				_ = w.addTree(event.Name)
+			}
+		}
+	}
+	return e
+}
@@ -0,0 +1,63 @@
+package fswatch
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	root := t.TempDir()
+	_ = os.Mkdir(path.Join(root, "secret"), 0755)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := make(chan *Event, 100)
+	ready := make(chan struct{})
+	o := &Options{
+		Recursive: true,
+		Skip:      func(p string) bool { return path.Base(p) == "secret" || path.Dir(p) == path.Join(root, "secret") },
+		Ready:     func() { close(ready) },
+	}
+	go func() {
+		_ = Watch(ctx, root, o, func(e *Event) error {
+			events <- e
+			return nil
+		})
+	}()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the watches")
+	}
+
+	expect := func(expected ...[2]string) {
+		for _, want := range expected {
+			select {
+			case e := <-events:
+				if e.Op != want[0] || path.Base(e.Path) != want[1] {
+					t.Fatalf("expected %s %s, got %+v", want[0], want[1], e)
+				}
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %s %s", want[0], want[1])
+			}
+		}
+	}
+	_ = os.WriteFile(path.Join(root, "secret", "key"), []byte("x"), 0644)
+	// the new dir is watched before its event is sent
+	_ = os.Mkdir(path.Join(root, "sub"), 0755)
+	expect([2]string{Create, "sub"})
+	// inotify drops the create & write events of a file which is already gone when they are read
+	_ = os.WriteFile(path.Join(root, "sub", "a.txt"), []byte("x"), 0644)
+	expect([2]string{Create, "a.txt"}, [2]string{Write, "a.txt"})
+	_ = os.Rename(path.Join(root, "sub", "a.txt"), path.Join(root, "sub", "b.txt"))
+	expect([2]string{Rename, "a.txt"}, [2]string{Create, "b.txt"})
+	_ = os.Remove(path.Join(root, "sub", "b.txt"))
+	expect([2]string{Remove, "b.txt"})
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %+v, the skipped dir must stay quiet", e)
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package hostbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/services/installer"
+	"github.com/NubeIO/platform/utils/checksum"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Documents are the names of the hosts file in a zip bundle, in the order they are looked up
+var Documents = []string{"hosts.yaml", "hosts.json"}
+
+// ArtifactPath is where the artifact of a host version is kept in a zip bundle
+func ArtifactPath(name, version, fileName string) (string, error) {
+	for field, value := range map[string]string{"name": name, "version": version, "artifact": fileName} {
+		if err := installer.ValidatePathName(field, value); err != nil {
+			return "", err
+		}
+	}
+	return path.Join("artifacts", name, version, fileName), nil
+}
+
+type Writer struct {
+	zip *zip.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zip: zip.NewWriter(w)}
+}
+
+func (w *Writer) Add(name string, r io.Reader) error {
+	entry, err := w.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, r)
+	return err
+}
+
+func (w *Writer) AddFile(name, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return w.Add(name, f)
+}
+
+func (w *Writer) Close() error {
+	return w.zip.Close()
+}
+
+type Reader struct {
+	zip *zip.Reader
+}
+
+// Open returns the hosts document, and the reader of the artifacts when the bundle is a zip; yaml & json bundles have none
+func Open(r io.ReaderAt, size int64) ([]byte, *Reader, error) {
+	head := make([]byte, 4)
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	if !bytes.Equal(head, []byte("PK\x03\x04")) {
+		document, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+		return document, nil, err
+	}
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := &Reader{zip: archive}
+	for _, name := range Documents {
+		if document, err := reader.read(name); err == nil {
+			return document, reader, nil
+		}
+	}
+	return nil, nil, errors.New(fmt.Sprintf("the zip has no %s or %s", Documents[0], Documents[1]))
+}
+
+func (r *Reader) read(name string) ([]byte, error) {
+	f, err := r.zip.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// ExtractArtifact copies the bundled artifact to destination once it matches the checksum the host declares,
+// it returns false when the artifact isn't bundled. The checksum comes from the hosts file of the same bundle,
+// so it catches a corrupted artifact but not a tampered bundle, which can change both
+func (r *Reader) ExtractArtifact(entry, destination, expected string) (bool, error) {
+	if expected == "" {
+		return false, errors.New(fmt.Sprintf("%s can't be verified, the host has no checksum", entry))
+	}
+	if _, _, err := checksum.Parse(expected); err != nil {
+		return false, err
+	}
+	f, err := r.zip.Open(entry)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	tmp, err := os.CreateTemp(path.Dir(destination), ".bundle-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, f); err != nil {
+		_ = tmp.Close()
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+	if err = checksum.Verify(tmp.Name(), expected); err != nil {
+		return false, errors.New(fmt.Sprintf("bundled artifact %s: %s", entry, err.Error()))
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp.Name(), destination)
+}
@@ -0,0 +1,87 @@
+package hostbundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func buildBundle(t *testing.T, artifact []byte) []byte {
+	var buffer bytes.Buffer
+	w := NewWriter(&buffer)
+	if err := w.Add("hosts.yaml", strings.NewReader("version: 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := ArtifactPath("demo", "v1.0.0", "demo.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Add(entry, bytes.NewReader(artifact)); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestOpen(t *testing.T) {
+	document, reader, err := Open(strings.NewReader("{\"version\": 1}"), 14)
+	if err != nil || reader != nil || string(document) != "{\"version\": 1}" {
+		t.Fatalf("expected a plain json bundle, got %q %v %v", document, reader, err)
+	}
+	data := buildBundle(t, []byte("artifact"))
+	document, reader, err = Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil || reader == nil || string(document) != "version: 1\n" {
+		t.Fatalf("expected a zip bundle, got %q %v %v", document, reader, err)
+	}
+}
+
+func TestExtractArtifact(t *testing.T) {
+	content := []byte("artifact")
+	sum := sha256.Sum256(content)
+	expected := "sha256:" + hex.EncodeToString(sum[:])
+	data := buildBundle(t, content)
+	_, reader, err := Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	destination := path.Join(dir, "demo.zip")
+	entry, _ := ArtifactPath("demo", "v1.0.0", "demo.zip")
+
+	wrong := "sha256:" + hex.EncodeToString(make([]byte, 32))
+	if _, err = reader.ExtractArtifact(entry, destination, wrong); err == nil {
+		t.Fatal("expected a checksum mismatch")
+	}
+	if _, err = reader.ExtractArtifact(entry, destination, ""); err == nil {
+		t.Fatal("expected an artifact without a checksum to be refused")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected a refused artifact to leave nothing behind, got %v", entries)
+	}
+
+	found, err := reader.ExtractArtifact(entry, destination, expected)
+	if err != nil || !found {
+		t.Fatalf("expected the artifact to be extracted, got %v %v", found, err)
+	}
+	if extracted, _ := os.ReadFile(destination); !bytes.Equal(extracted, content) {
+		t.Fatalf("unexpected content %q", extracted)
+	}
+	missing, _ := ArtifactPath("other", "v1.0.0", "other.zip")
+	if found, err = reader.ExtractArtifact(missing, path.Join(dir, "other.zip"), expected); err != nil || found {
+		t.Fatalf("expected a missing artifact to be reported as not bundled, got %v %v", found, err)
+	}
+}
+
+func TestArtifactPath(t *testing.T) {
+	for _, parts := range [][3]string{{"../..", "v1", "a.zip"}, {"demo", "..", "a.zip"}, {"demo", "v1", "../a.zip"}} {
+		if _, err := ArtifactPath(parts[0], parts[1], parts[2]); err == nil {
+			t.Errorf("expected %v to be refused", parts)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package hostdb
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SchemaVersion is the version written by this build, files with a lower version get migrated on load
+const SchemaVersion = 1
+
+// migrations[i] upgrades a document from version i to i+1
+var migrations = []func(doc map[string]interface{}) (map[string]interface{}, error){
+	migrateV0ToV1,
+}
+
+// migrateV0ToV1 wraps the legacy file, which was a bare map of instances
+func migrateV0ToV1(doc map[string]interface{}) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"version":   1,
+		"instances": doc,
+	}, nil
+}
+
+type Store struct {
+	Path     string
+	Backups  int // generations kept as <path>.1 ... <path>.<Backups>
+	FileMode os.FileMode
+}
+
+func New(path string, backups int) *Store {
+	if backups < 0 {
+		backups = 0
+	}
+	return &Store{
+		Path:     path,
+		Backups:  backups,
+		FileMode: 0644,
+	}
+}
+
+func (inst *Store) Exists() bool {
+	_, err := os.Stat(inst.Path)
+	return err == nil
+}
+
+// Load reads and migrates the file into out, it returns os.ErrNotExist when the file is missing
+func (inst *Store) Load(out interface{}) error {
+	data, err := os.ReadFile(inst.Path)
+	if err != nil {
+		return err
+	}
+	return Decode(data, out)
+}
+
+// Decode migrates the raw document to SchemaVersion and then decodes it into out
+func Decode(data []byte, out interface{}) error {
+	doc := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	version := documentVersion(doc)
+	if version > SchemaVersion {
+		return errors.New(fmt.Sprintf("db schema version %d is newer than the supported version %d", version, SchemaVersion))
+	}
+	for ; version < SchemaVersion; version++ {
+		var err error
+		doc, err = migrations[version](doc)
+		if err != nil {
+			return errors.New(fmt.Sprintf("failed to migrate db from version %d: %s", version, err.Error()))
+		}
+	}
+	migrated, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(migrated, out)
+}
+
+// documentVersion is 0 for the legacy files which don't have a version field
+func documentVersion(doc map[string]interface{}) int {
+	if version, ok := doc["version"].(int); ok {
+		if _, hasInstances := doc["instances"]; hasInstances {
+			return version
+		}
+	}
+	return 0
+}
+
+// Save marshals in and writes it atomically, the previous file is kept as the first backup generation
+func (inst *Store) Save(in interface{}) error {
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return inst.Write(data)
+}
+
+func (inst *Store) Write(data []byte) error {
+	dir := filepath.Dir(inst.Path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, fmt.Sprintf(".%s.tmp-*", filepath.Base(inst.Path)))
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpName, inst.FileMode); err != nil {
+		return err
+	}
+	if err = inst.rotateBackups(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpName, inst.Path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// rotateBackups shifts <path>.i to <path>.i+1 and links the current file as <path>.1, so the current file never goes missing
+func (inst *Store) rotateBackups() error {
+	if inst.Backups == 0 || !inst.Exists() {
+		return nil
+	}
+	for i := inst.Backups - 1; i >= 1; i-- {
+		if err := os.Rename(inst.backupPath(i), inst.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	first := inst.backupPath(1)
+	_ = os.Remove(first)
+	if err := os.Link(inst.Path, first); err != nil {
+		return copyFile(inst.Path, first)
+	}
+	return nil
+}
+
+func (inst *Store) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", inst.Path, i)
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
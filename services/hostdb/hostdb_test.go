@@ -0,0 +1,66 @@
+package hostdb
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+type testInstance struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+type testDB struct {
+	Version   int                      `yaml:"version"`
+	Instances map[string]*testInstance `yaml:"instances"`
+}
+
+func TestDecodeMigratesLegacyFile(t *testing.T) {
+	legacy := []byte("example:\n    name: example\n    port: 8080\n")
+	db := &testDB{}
+	if err := Decode(legacy, db); err != nil {
+		t.Fatal(err)
+	}
+	if db.Version != SchemaVersion {
+		t.Fatalf("expected version %d, got %d", SchemaVersion, db.Version)
+	}
+	if db.Instances["example"] == nil || db.Instances["example"].Port != 8080 {
+		t.Fatalf("unexpected instances: %+v", db.Instances)
+	}
+}
+
+func TestDecodeRejectsNewerVersion(t *testing.T) {
+	data := []byte("version: 99\ninstances: {}\n")
+	if err := Decode(data, &testDB{}); err == nil {
+		t.Fatal("expected an error for a newer schema version")
+	}
+}
+
+func TestSaveKeepsBackups(t *testing.T) {
+	dir := t.TempDir()
+	store := New(path.Join(dir, "db.yaml"), 2)
+	for port := 1; port <= 4; port++ {
+		db := &testDB{Version: SchemaVersion, Instances: map[string]*testInstance{"a": {Name: "a", Port: port}}}
+		if err := store.Save(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string]int{"db.yaml": 4, "db.yaml.1": 3, "db.yaml.2": 2}
+	for file, port := range expected {
+		db := &testDB{}
+		if err := New(path.Join(dir, file), 0).Load(db); err != nil {
+			t.Fatal(err)
+		}
+		if db.Instances["a"].Port != port {
+			t.Fatalf("expected port %d in %s, got %d", port, file, db.Instances["a"].Port)
+		}
+	}
+	if _, err := os.Stat(path.Join(dir, "db.yaml.3")); !os.IsNotExist(err) {
+		t.Fatal("expected only 2 backup generations")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Fatalf("expected no temp files to be left, got %d entries", len(entries))
+	}
+}
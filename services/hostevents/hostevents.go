@@ -0,0 +1,193 @@
+package hostevents
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	TypeStart       = "start"
+	TypeStartFailed = "start-failed"
+	TypeStop        = "stop"
+	TypeExit        = "exit"
+	TypeRestart     = "restart"
+	TypeGaveUp      = "gave-up"
+)
+
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	PID      int       `json:"pid,omitempty"`
+	ExitCode *int      `json:"exitCode,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// Manager keeps the lifecycle events of each instance in <Dir>/<name>.jsonl, the oldest ones are dropped past MaxEvents
+type Manager struct {
+	Dir       string
+	MaxEvents int
+	FileMode  os.FileMode
+	mutex     sync.Mutex
+	counts    map[string]int
+}
+
+func New(dir string, maxEvents int) *Manager {
+	if maxEvents <= 0 {
+		maxEvents = 1000
+	}
+	return &Manager{
+		Dir:       dir,
+		MaxEvents: maxEvents,
+		FileMode:  0644,
+		counts:    make(map[string]int),
+	}
+}
+
+func (inst *Manager) path(name string) string {
+	return path.Join(inst.Dir, fmt.Sprintf("%s.jsonl", name))
+}
+
+func (inst *Manager) Record(name string, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	if err = os.MkdirAll(inst.Dir, 0755); err != nil {
+		return err
+	}
+	count, found := inst.counts[name]
+	if !found {
+		events, err := inst.read(name)
+		if err != nil {
+			return err
+		}
+		count = len(events)
+		// a line cut by a power loss would swallow the next event
+		if cut, err := inst.endsWithCutLine(name); err != nil {
+			return err
+		} else if cut {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+	f, err := os.OpenFile(inst.path(name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, inst.FileMode)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	count++
+	// trimming rewrites the file, so let it grow to twice the limit first
+	if count > 2*inst.MaxEvents {
+		if count, err = inst.trim(name); err != nil {
+			return err
+		}
+	}
+	inst.counts[name] = count
+	return nil
+}
+
+// List returns the last n events, oldest first, all of them when n <= 0
+func (inst *Manager) List(name string, n int) ([]Event, error) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	events, err := inst.read(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > inst.MaxEvents {
+		events = events[len(events)-inst.MaxEvents:]
+	}
+	if n > 0 && len(events) > n {
+		events = events[len(events)-n:]
+	}
+	return events, nil
+}
+
+func (inst *Manager) Remove(name string) error {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	delete(inst.counts, name)
+	if err := os.Remove(inst.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// read skips lines which can't be decoded, e.g. one cut by a power loss
+func (inst *Manager) read(name string) ([]Event, error) {
+	data, err := os.ReadFile(inst.path(name))
+	if os.IsNotExist(err) {
+		return []Event{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var event Event
+		if err = json.Unmarshal(scanner.Bytes(), &event); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
+
+func (inst *Manager) endsWithCutLine(name string) (bool, error) {
+	f, err := os.Open(inst.path(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err = f.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+func (inst *Manager) trim(name string) (int, error) {
+	events, err := inst.read(name)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) > inst.MaxEvents {
+		events = events[len(events)-inst.MaxEvents:]
+	}
+	var buffer bytes.Buffer
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return 0, err
+		}
+		buffer.Write(append(line, '\n'))
+	}
+	tmp := inst.path(name) + ".tmp"
+	if err = os.WriteFile(tmp, buffer.Bytes(), inst.FileMode); err != nil {
+		return 0, err
+	}
+	return len(events), os.Rename(tmp, inst.path(name))
+}
@@ -0,0 +1,144 @@
+package hostevents
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func record(t *testing.T, manager *Manager, name string, pids ...int) {
+	for _, pid := range pids {
+		if err := manager.Record(name, Event{Type: TypeStart, PID: pid}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func pids(events []Event) []int {
+	values := make([]int, 0, len(events))
+	for _, event := range events {
+		values = append(values, event.PID)
+	}
+	return values
+}
+
+func lineCount(t *testing.T, file string) int {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		count++
+	}
+	return count
+}
+
+func TestList(t *testing.T) {
+	manager := New(t.TempDir(), 10)
+	events, err := manager.List("demo", 0)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected no events, got %v %v", events, err)
+	}
+	code := 1
+	if err = manager.Record("demo", Event{Type: TypeExit, PID: 1, ExitCode: &code, Message: "crashed"}); err != nil {
+		t.Fatal(err)
+	}
+	record(t, manager, "demo", 2, 3)
+	record(t, manager, "other", 4)
+	tests := []struct {
+		n    int
+		pids []int
+	}{
+		{0, []int{1, 2, 3}},
+		{2, []int{2, 3}},
+		{10, []int{1, 2, 3}},
+	}
+	for _, test := range tests {
+		events, err = manager.List("demo", test.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pids(events); !reflect.DeepEqual(got, test.pids) {
+			t.Errorf("last %d: expected %v, got %v", test.n, test.pids, got)
+		}
+	}
+	events, _ = manager.List("demo", 0)
+	first := events[0]
+	if first.Type != TypeExit || first.ExitCode == nil || *first.ExitCode != 1 || first.Message != "crashed" || first.Time.IsZero() {
+		t.Fatalf("unexpected event %+v", first)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	manager := New(t.TempDir(), 3)
+	record(t, manager, "demo", 1, 2, 3, 4, 5, 6)
+	if count := lineCount(t, manager.path("demo")); count != 6 {
+		t.Fatalf("expected the file to grow to twice the limit first, got %d lines", count)
+	}
+	events, _ := manager.List("demo", 0)
+	if got := pids(events); !reflect.DeepEqual(got, []int{4, 5, 6}) {
+		t.Fatalf("expected the last 3 events, got %v", got)
+	}
+	record(t, manager, "demo", 7)
+	if count := lineCount(t, manager.path("demo")); count != 3 {
+		t.Fatalf("expected the file to be trimmed to the limit, got %d lines", count)
+	}
+	events, _ = manager.List("demo", 0)
+	if got := pids(events); !reflect.DeepEqual(got, []int{5, 6, 7}) {
+		t.Fatalf("expected the last 3 events, got %v", got)
+	}
+}
+
+func TestRecordCountsExistingEvents(t *testing.T) {
+	dir := t.TempDir()
+	record(t, New(dir, 2), "demo", 1, 2, 3)
+	// a new manager, e.g. after a restart of the platform, reads the count from the file
+	manager := New(dir, 2)
+	record(t, manager, "demo", 4, 5)
+	if count := lineCount(t, manager.path("demo")); count != 2 {
+		t.Fatalf("expected the file to be trimmed, got %d lines", count)
+	}
+}
+
+func TestSkipsCutLines(t *testing.T) {
+	dir := t.TempDir()
+	record(t, New(dir, 10), "demo", 1)
+	manager := New(dir, 10)
+	f, err := os.OpenFile(manager.path("demo"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("{\"time\":\"20")
+	_ = f.Close()
+	// the platform restarts after a power loss, the next event goes on its own line
+	record(t, manager, "demo", 2, 3)
+	events, err := manager.List("demo", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pids(events); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("expected the cut line to be skipped, got %v", got)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	manager := New(t.TempDir(), 10)
+	record(t, manager, "demo", 1)
+	if err := manager.Remove("demo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(manager.path("demo")); !os.IsNotExist(err) {
+		t.Fatal("expected the events file to be removed")
+	}
+	if err := manager.Remove("demo"); err != nil {
+		t.Fatalf("removing twice should be fine, got %v", err)
+	}
+	events, _ := manager.List("demo", 0)
+	if len(events) != 0 {
+		t.Fatalf("expected no events, got %v", events)
+	}
+}
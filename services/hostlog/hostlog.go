@@ -0,0 +1,203 @@
+package hostlog
+
+import (
+	"fmt"
+	"github.com/NubeIO/platform/utils/tail"
+	"os"
+	"path"
+	"sync"
+)
+
+// Manager keeps a rotating log per instance under Dir
+type Manager struct {
+	Dir        string
+	MaxSize    int64 // bytes before the file gets rotated
+	MaxBackups int   // <name>.log.1 ... <name>.log.<MaxBackups>
+	FileMode   os.FileMode
+	mutex      sync.Mutex
+	logs       map[string]*Log
+}
+
+func New(dir string, maxSize int64, maxBackups int) *Manager {
+	if maxSize <= 0 {
+		maxSize = 10 * 1024 * 1024
+	}
+	if maxBackups < 0 {
+		maxBackups = 0
+	}
+	return &Manager{
+		Dir:        dir,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+		FileMode:   0644,
+		logs:       make(map[string]*Log),
+	}
+}
+
+func (inst *Manager) Get(name string) *Log {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	l, found := inst.logs[name]
+	if !found {
+		l = &Log{
+			path:        path.Join(inst.Dir, fmt.Sprintf("%s.log", name)),
+			maxSize:     inst.MaxSize,
+			maxBackups:  inst.MaxBackups,
+			fileMode:    inst.FileMode,
+			subscribers: make(map[chan []byte]struct{}),
+		}
+		inst.logs[name] = l
+	}
+	return l
+}
+
+// Remove closes the log and deletes its files
+func (inst *Manager) Remove(name string) error {
+	l := inst.Get(name)
+	inst.mutex.Lock()
+	delete(inst.logs, name)
+	inst.mutex.Unlock()
+	_ = l.Close()
+	for i := 0; i <= inst.MaxBackups; i++ {
+		if err := os.Remove(l.backupPath(i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Log is an io.WriteCloser, Close only releases the file handle so the log can be written again on the next start
+type Log struct {
+	path        string
+	maxSize     int64
+	maxBackups  int
+	fileMode    os.FileMode
+	mutex       sync.Mutex
+	file        *os.File
+	size        int64
+	subscribers map[chan []byte]struct{}
+}
+
+func (l *Log) Path() string {
+	return l.path
+}
+
+func (l *Log) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			return 0, err
+		}
+	}
+	if l.size+int64(len(p)) > l.maxSize && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	l.broadcast(p[:n])
+	return n, err
+}
+
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Tail returns the last n lines, going through the rotated files if the current one is too short
+func (l *Log) Tail(n int) ([]string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	lines := make([]string, 0)
+	for i := 0; i <= l.maxBackups && len(lines) < n; i++ {
+		older, err := tail.Lines(l.backupPath(i), n-len(lines))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		lines = append(older, lines...)
+	}
+	return lines, nil
+}
+
+// Subscribe returns a channel which receives everything written from now on, slow readers miss data rather than block the process
+func (l *Log) Subscribe() (<-chan []byte, func()) {
+	ch := make(chan []byte, 64)
+	l.mutex.Lock()
+	l.subscribers[ch] = struct{}{}
+	l.mutex.Unlock()
+	return ch, func() {
+		l.mutex.Lock()
+		delete(l.subscribers, ch)
+		l.mutex.Unlock()
+	}
+}
+
+func (l *Log) broadcast(p []byte) {
+	if len(l.subscribers) == 0 || len(p) == 0 {
+		return
+	}
+	data := make([]byte, len(p))
+	copy(data, p)
+	for ch := range l.subscribers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+func (l *Log) open() error {
+	if err := os.MkdirAll(path.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, l.fileMode)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	if l.maxBackups == 0 {
+		if err := os.Truncate(l.path, 0); err != nil {
+			return err
+		}
+		return l.open()
+	}
+	for i := l.maxBackups - 1; i >= 0; i-- {
+		if err := os.Rename(l.backupPath(i), l.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return l.open()
+}
+
+// backupPath returns <name>.log for 0 and <name>.log.<i> for the rotated ones
+func (l *Log) backupPath(i int) string {
+	if i == 0 {
+		return l.path
+	}
+	return fmt.Sprintf("%s.%d", l.path, i)
+}
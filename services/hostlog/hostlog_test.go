@@ -0,0 +1,127 @@
+package hostlog
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func writeLines(t *testing.T, l *Log, lines ...string) {
+	for _, line := range lines {
+		if _, err := l.Write([]byte(line + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotate(t *testing.T) {
+	manager := New(t.TempDir(), 8, 2)
+	l := manager.Get("demo")
+	writeLines(t, l, "aaa", "bbb", "ccc", "ddd", "eee", "fff", "ggg")
+	expected := map[string]string{
+		l.backupPath(0): "ggg\n",
+		l.backupPath(1): "eee\nfff\n",
+		l.backupPath(2): "ccc\nddd\n",
+	}
+	for file, content := range expected {
+		if got := readFile(t, file); got != content {
+			t.Errorf("%s: expected %q, got %q", file, content, got)
+		}
+	}
+	if _, err := os.Stat(l.backupPath(3)); !os.IsNotExist(err) {
+		t.Error("expected the oldest backup to be dropped")
+	}
+}
+
+func TestRotateWithoutBackups(t *testing.T) {
+	l := New(t.TempDir(), 8, 0).Get("demo")
+	writeLines(t, l, "aaa", "bbb", "ccc")
+	if got := readFile(t, l.Path()); got != "ccc\n" {
+		t.Fatalf("expected the log to be truncated, got %q", got)
+	}
+	if _, err := os.Stat(l.backupPath(1)); !os.IsNotExist(err) {
+		t.Error("expected no backup")
+	}
+}
+
+func TestReopenKeepsSize(t *testing.T) {
+	manager := New(t.TempDir(), 8, 1)
+	l := manager.Get("demo")
+	writeLines(t, l, "aaa")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, l, "bbb", "ccc")
+	if got := readFile(t, l.backupPath(1)); got != "aaa\nbbb\n" {
+		t.Fatalf("expected the reopened log to append and count its old size, got %q", got)
+	}
+}
+
+func TestTail(t *testing.T) {
+	manager := New(t.TempDir(), 8, 2)
+	l := manager.Get("demo")
+	lines, err := l.Tail(5)
+	if err != nil || len(lines) != 0 {
+		t.Fatalf("expected no lines before the first write, got %v %v", lines, err)
+	}
+	writeLines(t, l, "aaa", "bbb", "ccc", "ddd", "eee")
+	tests := []struct {
+		n     int
+		lines []string
+	}{
+		{0, []string{}},
+		{1, []string{"eee"}},
+		{3, []string{"ccc", "ddd", "eee"}},
+		{10, []string{"aaa", "bbb", "ccc", "ddd", "eee"}},
+	}
+	for _, test := range tests {
+		lines, err = l.Tail(test.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("last %d: expected %v, got %v", test.n, test.lines, lines)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	l := New(t.TempDir(), 1024, 1).Get("demo")
+	ch, unsubscribe := l.Subscribe()
+	writeLines(t, l, "hello")
+	if got := string(<-ch); got != "hello\n" {
+		t.Fatalf("unexpected data %q", got)
+	}
+	unsubscribe()
+	writeLines(t, l, "bye")
+	select {
+	case data := <-ch:
+		t.Fatalf("expected nothing after unsubscribe, got %q", data)
+	default:
+	}
+}
+
+func TestRemove(t *testing.T) {
+	manager := New(t.TempDir(), 8, 2)
+	l := manager.Get("demo")
+	writeLines(t, l, "aaa", "bbb", "ccc")
+	if err := manager.Remove("demo"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= 2; i++ {
+		if _, err := os.Stat(l.backupPath(i)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", l.backupPath(i))
+		}
+	}
+	if manager.Get("demo") == l {
+		t.Error("expected a new log after remove")
+	}
+}
@@ -1,14 +1,26 @@
 package installer
 
 import (
+	"errors"
 	"fmt"
 	"github.com/NubeIO/lib-utils-go/nuuid"
 	"github.com/NubeIO/platform/constants"
 	"os"
 	"path"
+	"regexp"
 	"time"
 )
 
+var pathName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidatePathName checks the names & versions which become a single element of the install, download or log paths
+func ValidatePathName(field, value string) error {
+	if !pathName.MatchString(value) || value == "." || value == ".." {
+		return errors.New(fmt.Sprintf("invalid %s %q, it can only contain letters, digits, '.', '_' and '-'", field, value))
+	}
+	return nil
+}
+
 func (inst *Installer) GetAppDataPath(appName string) string {
 	dataDirName := constants.GetDataDirNameFromAppName(appName)
 	return path.Join(inst.RootDir, dataDirName) // <root_dir>/rubix-wires
@@ -0,0 +1,16 @@
+package installer
+
+import "testing"
+
+func TestValidatePathName(t *testing.T) {
+	for _, value := range []string{"flow-framework", "v1.2.3-rc.1", "a_b", ".hidden"} {
+		if err := ValidatePathName("name", value); err != nil {
+			t.Errorf("%s: %s", value, err)
+		}
+	}
+	for _, value := range []string{"", ".", "..", "../..", "a/b", "a b", "a\\b", "v1\n"} {
+		if err := ValidatePathName("name", value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
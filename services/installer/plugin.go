@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/NubeIO/platform/dto"
+	"github.com/NubeIO/platform/utils/checksum"
 	"io/ioutil"
 	"strings"
 )
@@ -77,6 +78,9 @@ func (inst *Installer) GetPluginsStorePlugins() ([]BuildDetails, error) {
 	}
 	plugins := make([]BuildDetails, 0)
 	for _, file := range files {
+		if checksum.IsSidecar(file.Name()) {
+			continue
+		}
 		plugins = append(plugins, *inst.GetZipBuildDetails(file.Name()))
 	}
 	return plugins, err
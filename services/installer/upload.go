@@ -1,7 +1,10 @@
 package installer
 
 import (
+	"errors"
+	"github.com/NubeIO/lib-files/fileutils"
 	"github.com/NubeIO/platform/dto"
+	"github.com/NubeIO/platform/utils/checksum"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"mime/multipart"
@@ -27,6 +30,50 @@ func (inst *Installer) Upload(zip *multipart.FileHeader) (*dto.UploadResponse, e
 	}, nil
 }
 
+// UploadLocal links a file which is already on the device into a new tmp dir, the same way Upload does with a form file.
+// The source is left in place so a chunked upload can be retried when a later step fails
+func (inst *Installer) UploadLocal(source string) (*dto.UploadResponse, error) {
+	tmpDir, err := inst.MakeTmpDirUpload()
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("link build %s to tmp dir: %s", source, tmpDir)
+	fileName := path.Base(source)
+	uploadedFile := path.Join(tmpDir, fileName)
+	if err = os.Link(source, uploadedFile); err != nil {
+		if err = fileutils.CopyFile(source, uploadedFile); err != nil {
+			_ = os.RemoveAll(tmpDir)
+			return nil, err
+		}
+	}
+	return &dto.UploadResponse{
+		FileName:     fileName,
+		TmpFile:      tmpDir,
+		UploadedFile: uploadedFile,
+	}, nil
+}
+
+// UploadApp takes the form file of the upload, or its local file when there is no form file
+func (inst *Installer) UploadApp(app *dto.Upload) (*dto.UploadResponse, error) {
+	var resp *dto.UploadResponse
+	var err error
+	if app.File == nil && app.LocalFile != "" {
+		resp, err = inst.UploadLocal(app.LocalFile)
+	} else if app.File == nil {
+		return nil, errors.New("file can not be empty")
+	} else {
+		resp, err = inst.Upload(app.File)
+	}
+	if err != nil || app.Checksum == "" {
+		return resp, err
+	}
+	if err = checksum.Verify(resp.UploadedFile, app.Checksum); err != nil {
+		_ = os.RemoveAll(resp.TmpFile)
+		return nil, err
+	}
+	return resp, nil
+}
+
 // SaveUploadedFile uploads the form file to specific dst.
 // combination's of file name and the destination and will save file as: /data/my-file
 // returns the filename and path as a string and any error
@@ -0,0 +1,182 @@
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CgroupDir is the cgroup v2 parent of the supervised processes, each one gets <CgroupDir>/<name>.
+// It's outside the cgroup of the platform service, so the processes outlive a stop or a crash of the platform
+var CgroupDir = "/sys/fs/cgroup/nubeio-hosts"
+
+// LeftoverTimeout is how long the processes left in a cgroup get to die before the host start fails
+var LeftoverTimeout = 5 * time.Second
+
+const cpuPeriod = 100000 // microseconds
+
+type Limits struct {
+	MemoryMaxMB     int    `json:"memoryMaxMB,omitempty" yaml:"memory_max_mb,omitempty"`
+	CPUQuotaPercent int    `json:"cpuQuotaPercent,omitempty" yaml:"cpu_quota_percent,omitempty"` // 100 is one full core
+	Nice            int    `json:"nice,omitempty" yaml:"nice,omitempty"`                         // -20 to 19
+	MaxOpenFiles    uint64 `json:"maxOpenFiles,omitempty" yaml:"max_open_files,omitempty"`
+}
+
+func (l *Limits) Validate() error {
+	if l.MemoryMaxMB < 0 || l.CPUQuotaPercent < 0 {
+		return errors.New("memory max and cpu quota can not be negative")
+	}
+	if l.Nice < -20 || l.Nice > 19 {
+		return errors.New("nice must be between -20 and 19")
+	}
+	return nil
+}
+
+func (l *Limits) needsCgroup() bool {
+	return l.MemoryMaxMB > 0 || l.CPUQuotaPercent > 0
+}
+
+// Cgroup prepares the cgroup of the process and opens it, so that the process can be started right inside it
+// and none of its children escape the limits. It returns nil when no memory or cpu limit is set
+func Cgroup(name string, l *Limits) (*os.File, error) {
+	if l == nil || !l.needsCgroup() {
+		return nil, nil
+	}
+	dir, err := prepareCgroup(name, l)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to apply cgroup limits: %s", err.Error()))
+	}
+	return os.Open(dir)
+}
+
+// Apply sets the nice level & the open files rlimit of the calling process, it runs right before the exec of the command
+// so that they are inherited from the start. The nice level is per thread, the caller has to lock its os thread
+func Apply(l *Limits) error {
+	if l == nil {
+		return nil
+	}
+	if l.Nice != 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, l.Nice); err != nil {
+			return errors.New(fmt.Sprintf("failed to set nice %d: %s", l.Nice, err.Error()))
+		}
+	}
+	if l.MaxOpenFiles > 0 {
+		rlimit := &unix.Rlimit{Cur: l.MaxOpenFiles, Max: l.MaxOpenFiles}
+		if err := unix.Setrlimit(unix.RLIMIT_NOFILE, rlimit); err != nil {
+			return errors.New(fmt.Sprintf("failed to set max open files %d: %s", l.MaxOpenFiles, err.Error()))
+		}
+	}
+	return nil
+}
+
+// Release removes the cgroup once its processes are gone
+func Release(name string) error {
+	err := unix.Rmdir(path.Join(CgroupDir, name))
+	if err != nil && !errors.Is(err, unix.ENOENT) {
+		return err
+	}
+	return nil
+}
+
+func prepareCgroup(name string, l *Limits) (string, error) {
+	root := path.Dir(CgroupDir)
+	if _, err := os.Stat(path.Join(root, "cgroup.controllers")); err != nil {
+		return "", errors.New("cgroup v2 is not mounted, use the systemd mode for memory & cpu limits")
+	}
+	if err := os.MkdirAll(CgroupDir, 0755); err != nil {
+		return "", err
+	}
+	// controllers have to be enabled on every level down to the parent of the process cgroup
+	for _, dir := range []string{root, CgroupDir} {
+		if err := enableControllers(dir, "cpu", "memory"); err != nil {
+			return "", err
+		}
+	}
+	dir := path.Join(CgroupDir, name)
+	if err := killLeftovers(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	memoryMax := "max"
+	if l.MemoryMaxMB > 0 {
+		memoryMax = strconv.Itoa(l.MemoryMaxMB * 1024 * 1024)
+	}
+	if err := os.WriteFile(path.Join(dir, "memory.max"), []byte(memoryMax), 0644); err != nil {
+		return "", err
+	}
+	cpuMax := fmt.Sprintf("max %d", cpuPeriod)
+	if l.CPUQuotaPercent > 0 {
+		cpuMax = fmt.Sprintf("%d %d", l.CPUQuotaPercent*cpuPeriod/100, cpuPeriod)
+	}
+	if err := os.WriteFile(path.Join(dir, "cpu.max"), []byte(cpuMax), 0644); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// killLeftovers kills what is still running in the cgroup of a host, e.g. the processes of a platform which was stopped
+// or crashed, so that the host doesn't run twice
+func killLeftovers(dir string) error {
+	deadline := time.Now().Add(LeftoverTimeout)
+	for {
+		data, err := os.ReadFile(path.Join(dir, "cgroup.procs"))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		alive := 0
+		for _, field := range strings.Fields(string(data)) {
+			pid, err := strconv.Atoi(field)
+			if err != nil || pid <= 0 {
+				continue
+			}
+			if err = unix.Kill(pid, unix.SIGKILL); err == nil {
+				alive++
+			} else if !errors.Is(err, unix.ESRCH) {
+				return errors.New(fmt.Sprintf("failed to kill the leftover process %d in %s: %s", pid, dir, err.Error()))
+			}
+		}
+		if alive == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("%d leftover processes in %s didn't exit", alive, dir))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func enableControllers(dir string, controllers ...string) error {
+	data, err := os.ReadFile(path.Join(dir, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	enabled := strings.Fields(string(data))
+	for _, controller := range controllers {
+		if containsString(enabled, controller) {
+			continue
+		}
+		if err = os.WriteFile(path.Join(dir, "cgroup.subtree_control"), []byte("+"+controller), 0644); err != nil {
+			return errors.New(fmt.Sprintf("failed to enable the %s controller in %s: %s", controller, dir, err.Error()))
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package limits
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		limits Limits
+		valid  bool
+	}{
+		{Limits{}, true},
+		{Limits{MemoryMaxMB: 256, CPUQuotaPercent: 150, Nice: 10, MaxOpenFiles: 1024}, true},
+		{Limits{Nice: -20}, true},
+		{Limits{Nice: 19}, true},
+		{Limits{Nice: -21}, false},
+		{Limits{Nice: 20}, false},
+		{Limits{MemoryMaxMB: -1}, false},
+		{Limits{CPUQuotaPercent: -1}, false},
+	}
+	for _, test := range tests {
+		if err := test.limits.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %v, got %v", test.limits, test.valid, err)
+		}
+	}
+}
+
+func fakeCgroupRoot(t *testing.T, rootControllers string) string {
+	root := t.TempDir()
+	CgroupDir = path.Join(root, "nubeio-hosts")
+	t.Cleanup(func() { CgroupDir = "/sys/fs/cgroup/nubeio-hosts" })
+	if err := os.MkdirAll(CgroupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{
+		path.Join(root, "cgroup.controllers"):          "cpu memory io",
+		path.Join(root, "cgroup.subtree_control"):      rootControllers,
+		path.Join(CgroupDir, "cgroup.subtree_control"): "",
+	} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func readFile(t *testing.T, file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCgroup(t *testing.T) {
+	root := fakeCgroupRoot(t, "cpu memory")
+	f, err := Cgroup("demo", &Limits{MemoryMaxMB: 64, CPUQuotaPercent: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dir := path.Join(CgroupDir, "demo")
+	if f.Name() != dir {
+		t.Fatalf("expected %s to be opened, got %s", dir, f.Name())
+	}
+	if got := readFile(t, path.Join(dir, "memory.max")); got != "67108864" {
+		t.Errorf("unexpected memory.max %q", got)
+	}
+	if got := readFile(t, path.Join(dir, "cpu.max")); got != "50000 100000" {
+		t.Errorf("unexpected cpu.max %q", got)
+	}
+	if got := readFile(t, path.Join(root, "cgroup.subtree_control")); got != "cpu memory" {
+		t.Errorf("enabled controllers shouldn't be written again, got %q", got)
+	}
+	// the fake file keeps the last write only, a real cgroup fs adds each controller
+	if got := readFile(t, path.Join(CgroupDir, "cgroup.subtree_control")); got != "+memory" {
+		t.Errorf("expected the controllers to be enabled on the parent, got %q", got)
+	}
+	if _, err = os.Stat(path.Join(dir, "cgroup.procs")); err == nil {
+		t.Error("the process has to be started in the cgroup, not moved into it")
+	}
+}
+
+func TestCgroupUnlimited(t *testing.T) {
+	fakeCgroupRoot(t, "")
+	f, err := Cgroup("demo", &Limits{MemoryMaxMB: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got := readFile(t, path.Join(CgroupDir, "demo", "cpu.max")); got != "max 100000" {
+		t.Errorf("expected no cpu quota, got %q", got)
+	}
+	for _, l := range []*Limits{nil, {Nice: 5, MaxOpenFiles: 1024}} {
+		if f, err = Cgroup("other", l); f != nil || err != nil {
+			t.Errorf("expected no cgroup for %+v, got %v %v", l, f, err)
+		}
+	}
+}
+
+func TestCgroupNotMounted(t *testing.T) {
+	CgroupDir = path.Join(t.TempDir(), "nubeio-hosts")
+	t.Cleanup(func() { CgroupDir = "/sys/fs/cgroup/nubeio-hosts" })
+	if _, err := Cgroup("demo", &Limits{MemoryMaxMB: 64}); err == nil {
+		t.Fatal("expected an error without cgroup v2")
+	}
+}
+
+func TestCgroupKillsLeftovers(t *testing.T) {
+	fakeCgroupRoot(t, "cpu memory")
+	dir := path.Join(CgroupDir, "demo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the processes of a previous platform run which are still in the cgroup
+	exited := make(chan error, 2)
+	pids := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		cmd := exec.Command("sleep", "30")
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = cmd.Process.Kill() })
+		go func() { exited <- cmd.Wait() }()
+		pids = append(pids, strconv.Itoa(cmd.Process.Pid))
+	}
+	if err := os.WriteFile(path.Join(dir, "cgroup.procs"), []byte(strings.Join(pids, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Cgroup("demo", &Limits{MemoryMaxMB: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	for i := 0; i < 2; i++ {
+		select {
+		case err = <-exited:
+			if err == nil {
+				t.Fatal("expected the leftover to be killed")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the leftovers to be killed before the cgroup is used again")
+		}
+	}
+}
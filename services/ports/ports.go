@@ -0,0 +1,159 @@
+package ports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	TCP = "tcp"
+	UDP = "udp"
+)
+
+// ProcDir is the procfs mount point
+var ProcDir = "/proc"
+
+const tcpListen = "0A"
+
+type Listener struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Inode    uint64 `json:"inode"`
+	PID      int    `json:"pid,omitempty"`
+	Process  string `json:"process,omitempty"`
+}
+
+func (l *Listener) Owner() string {
+	if l.PID == 0 {
+		return "an unknown process"
+	}
+	return fmt.Sprintf("process %s (pid %d)", l.Process, l.PID)
+}
+
+// Listening returns the listening tcp sockets and the bound udp sockets, for both ipv4 & ipv6
+func Listening(protocol string) ([]*Listener, error) {
+	listeners := make([]*Listener, 0)
+	for _, file := range []string{protocol, protocol + "6"} {
+		f, err := os.Open(path.Join(ProcDir, "net", file))
+		if os.IsNotExist(err) {
+			continue // no ipv6
+		}
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseProcNet(f, protocol)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, parsed...)
+	}
+	return listeners, nil
+}
+
+// Find returns the socket bound on the port with its owning process, nil if the port is free
+func Find(protocol string, port int) (*Listener, error) {
+	listeners, err := Listening(protocol)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range listeners {
+		if l.Port == port {
+			l.PID, l.Process = owner(l.Inode)
+			return l, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseProcNet parses the format of /proc/net/tcp, the first line is the header:
+// sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+func parseProcNet(r io.Reader, protocol string) ([]*Listener, error) {
+	listeners := make([]*Listener, 0)
+	scanner := bufio.NewScanner(r)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if protocol == TCP && fields[3] != tcpListen {
+			continue
+		}
+		address, port, err := parseAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		if port == 0 {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, &Listener{Protocol: protocol, Address: address, Port: port, Inode: inode})
+	}
+	return listeners, scanner.Err()
+}
+
+// parseAddress decodes 0100007F:1F90 into 127.0.0.1 & 8080, ipv6 addresses are left as hex
+func parseAddress(value string) (string, int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid address %s", value)
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", 0, err
+	}
+	address := parts[0]
+	if len(address) == 8 {
+		ip, err := strconv.ParseUint(address, 16, 32)
+		if err != nil {
+			return "", 0, err
+		}
+		address = fmt.Sprintf("%d.%d.%d.%d", ip&0xff, ip>>8&0xff, ip>>16&0xff, ip>>24&0xff)
+	}
+	return address, int(port), nil
+}
+
+// owner walks /proc/<pid>/fd looking for the socket inode, processes we can't read are skipped
+func owner(inode uint64) (int, string) {
+	if inode == 0 {
+		return 0, ""
+	}
+	target := fmt.Sprintf("socket:[%d]", inode)
+	entries, err := os.ReadDir(ProcDir)
+	if err != nil {
+		return 0, ""
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := path.Join(ProcDir, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(path.Join(fdDir, fd.Name()))
+			if err == nil && link == target {
+				comm, _ := os.ReadFile(path.Join(ProcDir, entry.Name(), "comm"))
+				return pid, strings.TrimSpace(string(comm))
+			}
+		}
+	}
+	return 0, ""
+}
@@ -0,0 +1,125 @@
+package ports
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const header = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+const procNetTCP = header +
+	"   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 12345 1 0000000000000000 100 0 0 10 0\n" +
+	"   1: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2222 1 0000000000000000 100 0 0 10 0\n" +
+	"   2: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 3333 1 0000000000000000 20 4 30 10 -1\n"
+
+const procNetTCP6 = header +
+	"   0: 00000000000000000000000000000000:0050 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 4444 1 0000000000000000 100 0 0 10 0\n"
+
+const procNetUDP = header +
+	"  10: 00000000:14E9 00000000:0000 07 00000000:00000000 00:00000000 00000000   102        0 5555 2 0000000000000000 0\n" +
+	"  11: 00000000:0000 00000000:0000 07 00000000:00000000 00:00000000 00000000   102        0 6666 2 0000000000000000 0\n"
+
+func TestParseProcNet(t *testing.T) {
+	tests := []struct {
+		protocol  string
+		content   string
+		listeners []Listener
+	}{
+		{TCP, procNetTCP, []Listener{
+			{Protocol: TCP, Address: "127.0.0.1", Port: 8080, Inode: 12345},
+			{Protocol: TCP, Address: "0.0.0.0", Port: 22, Inode: 2222},
+		}},
+		{TCP, procNetTCP6, []Listener{
+			{Protocol: TCP, Address: "00000000000000000000000000000000", Port: 80, Inode: 4444},
+		}},
+		{UDP, procNetUDP, []Listener{
+			{Protocol: UDP, Address: "0.0.0.0", Port: 5353, Inode: 5555},
+		}},
+		{TCP, header, []Listener{}},
+		{TCP, header + "   0: short line\n", []Listener{}},
+	}
+	for _, test := range tests {
+		parsed, err := parseProcNet(strings.NewReader(test.content), test.protocol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]Listener, 0, len(parsed))
+		for _, l := range parsed {
+			got = append(got, *l)
+		}
+		if !reflect.DeepEqual(got, test.listeners) {
+			t.Errorf("expected %+v, got %+v", test.listeners, got)
+		}
+	}
+	if _, err := parseProcNet(strings.NewReader(header+strings.Replace(procNetTCP[len(header):], "1F90", "ZZZZ", 1)), TCP); err == nil {
+		t.Error("expected an invalid port to fail")
+	}
+}
+
+func fakeProc(t *testing.T) {
+	ProcDir = t.TempDir()
+	t.Cleanup(func() { ProcDir = "/proc" })
+	files := map[string]string{
+		"net/tcp":   procNetTCP,
+		"net/udp":   procNetUDP,
+		"1234/comm": "rubix-os\n",
+	}
+	for file, content := range files {
+		filePath := path.Join(ProcDir, file)
+		if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, fd := range []string{"4321/fd/3", "1234/fd/0", "1234/fd/7"} {
+		if err := os.MkdirAll(path.Join(ProcDir, path.Dir(fd)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"4321/fd/3": "socket:[999]",
+		"1234/fd/0": "/dev/null",
+		"1234/fd/7": "socket:[12345]",
+	}
+	for fd, target := range links {
+		if err := os.Symlink(target, path.Join(ProcDir, fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListening(t *testing.T) {
+	fakeProc(t)
+	listeners, err := Listening(TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("expected the ipv4 listeners without a tcp6 file, got %d", len(listeners))
+	}
+}
+
+func TestFind(t *testing.T) {
+	fakeProc(t)
+	l, err := Find(TCP, 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l == nil || l.PID != 1234 || l.Process != "rubix-os" {
+		t.Fatalf("expected the owner of port 8080, got %+v", l)
+	}
+	if owner := l.Owner(); owner != "process rubix-os (pid 1234)" {
+		t.Fatalf("unexpected owner %q", owner)
+	}
+	if l, err = Find(TCP, 22); err != nil || l == nil || l.PID != 0 || l.Owner() != "an unknown process" {
+		t.Fatalf("expected a listener without a known owner, got %+v %v", l, err)
+	}
+	if l, err = Find(UDP, 8080); err != nil || l != nil {
+		t.Fatalf("expected a free udp port, got %+v %v", l, err)
+	}
+}
@@ -0,0 +1,118 @@
+package probe
+
+import (
+	"sync"
+	"time"
+)
+
+type Result struct {
+	Type                string     `json:"type"`
+	Healthy             bool       `json:"healthy"`
+	Checked             bool       `json:"checked"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	FailureThreshold    int        `json:"failureThreshold"`
+	LastCheck           *time.Time `json:"lastCheck,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+}
+
+// Failing is true once the failures reach the threshold
+func (r *Result) Failing() bool {
+	return r.ConsecutiveFailures >= r.FailureThreshold
+}
+
+type runner struct {
+	config Config
+	host   string
+	port   int
+	mutex  sync.Mutex
+	result Result
+	stop   chan struct{}
+}
+
+// Monitor runs the probes in the background, keyed by the instance name & probe kind
+type Monitor struct {
+	Host    string
+	mutex   sync.Mutex
+	runners map[string]*runner
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		Host:    "127.0.0.1",
+		runners: make(map[string]*runner),
+	}
+}
+
+// Watch (re)starts the probe, a previous probe with the same key is stopped
+func (inst *Monitor) Watch(key string, config Config, port int) {
+	inst.Unwatch(key)
+	r := &runner{
+		config: config,
+		host:   inst.Host,
+		port:   port,
+		result: Result{Type: config.Type, FailureThreshold: config.failureThreshold()},
+		stop:   make(chan struct{}),
+	}
+	inst.mutex.Lock()
+	inst.runners[key] = r
+	inst.mutex.Unlock()
+	go r.run()
+}
+
+func (inst *Monitor) Unwatch(key string) {
+	inst.mutex.Lock()
+	r, found := inst.runners[key]
+	delete(inst.runners, key)
+	inst.mutex.Unlock()
+	if found {
+		close(r.stop)
+	}
+}
+
+func (inst *Monitor) Result(key string) *Result {
+	inst.mutex.Lock()
+	r, found := inst.runners[key]
+	inst.mutex.Unlock()
+	if !found {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	result := r.result
+	return &result
+}
+
+func (r *runner) run() {
+	select {
+	case <-time.After(time.Duration(r.config.InitialDelaySeconds) * time.Second):
+	case <-r.stop:
+		return
+	}
+	ticker := time.NewTicker(r.config.interval())
+	defer ticker.Stop()
+	for {
+		r.check()
+		select {
+		case <-ticker.C:
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *runner) check() {
+	err := Check(&r.config, r.host, r.port)
+	now := time.Now()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.result.Checked = true
+	r.result.LastCheck = &now
+	if err != nil {
+		r.result.ConsecutiveFailures++
+		r.result.LastError = err.Error()
+	} else {
+		r.result.ConsecutiveFailures = 0
+		r.result.LastError = ""
+	}
+	r.result.Healthy = !r.result.Failing()
+}
@@ -0,0 +1,138 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	TypeTCP  = "tcp"
+	TypeHTTP = "http"
+	TypeUDP  = "udp"
+)
+
+type Config struct {
+	Type                string `json:"type,omitempty" yaml:"type,omitempty"` // tcp, http or udp, defaults from the instance transport
+	Path                string `json:"path,omitempty" yaml:"path,omitempty"` // http only, defaults to /
+	Payload             string `json:"payload,omitempty" yaml:"payload,omitempty"`
+	ExpectResponse      bool   `json:"expectResponse,omitempty" yaml:"expect_response,omitempty"` // udp only, otherwise silence counts as healthy
+	InitialDelaySeconds int    `json:"initialDelaySeconds,omitempty" yaml:"initial_delay_seconds,omitempty"`
+	IntervalSeconds     int    `json:"intervalSeconds,omitempty" yaml:"interval_seconds,omitempty"`
+	TimeoutSeconds      int    `json:"timeoutSeconds,omitempty" yaml:"timeout_seconds,omitempty"`
+	FailureThreshold    int    `json:"failureThreshold,omitempty" yaml:"failure_threshold,omitempty"`
+}
+
+// TypeFromTransport maps the instance transport into a probe type
+func TypeFromTransport(transport string) string {
+	switch strings.ToLower(transport) {
+	case "udp":
+		return TypeUDP
+	case "http", "https":
+		return TypeHTTP
+	default:
+		return TypeTCP
+	}
+}
+
+func (c *Config) interval() time.Duration {
+	if c.IntervalSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+func (c *Config) timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+func (c *Config) failureThreshold() int {
+	if c.FailureThreshold <= 0 {
+		return 3
+	}
+	return c.FailureThreshold
+}
+
+func (c *Config) Validate() error {
+	switch c.Type {
+	case "", TypeTCP, TypeHTTP, TypeUDP:
+	default:
+		return errors.New(fmt.Sprintf("probe type must be one of %s, %s, %s", TypeTCP, TypeHTTP, TypeUDP))
+	}
+	if c.IntervalSeconds < 0 || c.TimeoutSeconds < 0 || c.FailureThreshold < 0 || c.InitialDelaySeconds < 0 {
+		return errors.New("probe interval, timeout, initial delay and failure threshold can not be negative")
+	}
+	return nil
+}
+
+// Check runs the probe once against host:port
+func Check(c *Config, host string, port int) error {
+	if port <= 0 {
+		return errors.New("port is not set")
+	}
+	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	switch c.Type {
+	case TypeHTTP:
+		return checkHTTP(c, address)
+	case TypeUDP:
+		return checkUDP(c, address)
+	default:
+		return checkTCP(c, address)
+	}
+}
+
+func checkTCP(c *Config, address string) error {
+	conn, err := net.DialTimeout("tcp", address, c.timeout())
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func checkHTTP(c *Config, address string) error {
+	p := c.Path
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	client := http.Client{Timeout: c.timeout()}
+	resp, err := client.Get(fmt.Sprintf("http://%s%s", address, p))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return errors.New(fmt.Sprintf("unexpected status code %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// checkUDP sends the payload, a closed port answers with an ICMP unreachable which shows up as a read error
+func checkUDP(c *Config, address string) error {
+	conn, err := net.DialTimeout("udp", address, c.timeout())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(c.timeout())); err != nil {
+		return err
+	}
+	if _, err = conn.Write([]byte(c.Payload)); err != nil {
+		return err
+	}
+	buffer := make([]byte, 512)
+	_, err = conn.Read(buffer)
+	var netErr net.Error
+	if err != nil && errors.As(err, &netErr) && netErr.Timeout() {
+		if c.ExpectResponse {
+			return errors.New("no response received")
+		}
+		return nil
+	}
+	return err
+}
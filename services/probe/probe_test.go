@@ -0,0 +1,163 @@
+package probe
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func listenTCP(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// closedPort returns a port nothing listens on
+func closedPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	return port
+}
+
+// listenUDP answers every packet when reply is set, and stays silent otherwise
+func listenUDP(t *testing.T, reply bool) int {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if reply {
+				_, _ = conn.WriteTo(buffer[:n], addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func serverPort(t *testing.T, server *httptest.Server) int {
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	tests := []struct {
+		name    string
+		config  Config
+		port    int
+		healthy bool
+	}{
+		{"tcp open", Config{}, listenTCP(t), true},
+		{"tcp closed", Config{Type: TypeTCP}, closedPort(t), false},
+		{"no port", Config{}, 0, false},
+		{"http ok", Config{Type: TypeHTTP, Path: "health"}, serverPort(t, server), true},
+		{"http bad status", Config{Type: TypeHTTP, Path: "/"}, serverPort(t, server), false},
+		{"udp answer", Config{Type: TypeUDP, Payload: "ping", ExpectResponse: true, TimeoutSeconds: 1}, listenUDP(t, true), true},
+		{"udp silent", Config{Type: TypeUDP, Payload: "ping", TimeoutSeconds: 1}, listenUDP(t, false), true},
+		{"udp silent expecting an answer", Config{Type: TypeUDP, Payload: "ping", ExpectResponse: true, TimeoutSeconds: 1}, listenUDP(t, false), false},
+	}
+	for _, test := range tests {
+		err := Check(&test.config, "127.0.0.1", test.port)
+		if (err == nil) != test.healthy {
+			t.Errorf("%s: expected healthy %v, got %v", test.name, test.healthy, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{}, true},
+		{Config{Type: TypeHTTP, IntervalSeconds: 5}, true},
+		{Config{Type: "icmp"}, false},
+		{Config{IntervalSeconds: -1}, false},
+		{Config{FailureThreshold: -1}, false},
+	}
+	for _, test := range tests {
+		if err := test.config.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %v, got %v", test.config, test.valid, err)
+		}
+	}
+}
+
+func TestTypeFromTransport(t *testing.T) {
+	for transport, expected := range map[string]string{"": TypeTCP, "tcp": TypeTCP, "UDP": TypeUDP, "https": TypeHTTP} {
+		if got := TypeFromTransport(transport); got != expected {
+			t.Errorf("%q: expected %s, got %s", transport, expected, got)
+		}
+	}
+}
+
+func TestRunnerThreshold(t *testing.T) {
+	port := closedPort(t)
+	r := &runner{config: Config{FailureThreshold: 2}, host: "127.0.0.1", port: port}
+	r.result.FailureThreshold = r.config.failureThreshold()
+	r.check()
+	if !r.result.Checked || !r.result.Healthy || r.result.ConsecutiveFailures != 1 || r.result.LastError == "" {
+		t.Fatalf("expected one failure below the threshold to stay healthy, got %+v", r.result)
+	}
+	r.check()
+	if r.result.Healthy || r.result.ConsecutiveFailures != 2 {
+		t.Fatalf("expected the threshold to make it unhealthy, got %+v", r.result)
+	}
+	r.port = listenTCP(t)
+	r.check()
+	if !r.result.Healthy || r.result.ConsecutiveFailures != 0 || r.result.LastError != "" {
+		t.Fatalf("expected a success to reset the failures, got %+v", r.result)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	monitor := NewMonitor()
+	monitor.Watch("demo", Config{}, listenTCP(t))
+	deadline := time.Now().Add(2 * time.Second)
+	result := monitor.Result("demo")
+	for (result == nil || !result.Checked) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		result = monitor.Result("demo")
+	}
+	if result == nil || !result.Checked || !result.Healthy || result.FailureThreshold != 3 {
+		t.Fatalf("expected a healthy first check, got %+v", result)
+	}
+	monitor.Unwatch("demo")
+	if monitor.Result("demo") != nil {
+		t.Fatal("expected no result once unwatched")
+	}
+}
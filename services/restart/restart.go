@@ -0,0 +1,176 @@
+package restart
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	Never     = "never"
+	OnFailure = "on-failure"
+	Always    = "always"
+)
+
+type Policy struct {
+	Mode                string `json:"mode,omitempty" yaml:"mode,omitempty"` // never, on-failure or always, defaults to never
+	InitialDelaySeconds int    `json:"initialDelaySeconds,omitempty" yaml:"initial_delay_seconds,omitempty"`
+	MaxDelaySeconds     int    `json:"maxDelaySeconds,omitempty" yaml:"max_delay_seconds,omitempty"`
+	MaxCrashes          int    `json:"maxCrashes,omitempty" yaml:"max_crashes,omitempty"` // crashes allowed within the window before giving up
+	WindowSeconds       int    `json:"windowSeconds,omitempty" yaml:"window_seconds,omitempty"`
+}
+
+func (p *Policy) mode() string {
+	if p == nil || p.Mode == "" {
+		return Never
+	}
+	return p.Mode
+}
+
+func (p *Policy) InitialDelay() time.Duration {
+	if p.InitialDelaySeconds <= 0 {
+		return time.Second
+	}
+	return time.Duration(p.InitialDelaySeconds) * time.Second
+}
+
+func (p *Policy) MaxDelay() time.Duration {
+	if p.MaxDelaySeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(p.MaxDelaySeconds) * time.Second
+}
+
+func (p *Policy) Crashes() int {
+	if p.MaxCrashes <= 0 {
+		return 5
+	}
+	return p.MaxCrashes
+}
+
+func (p *Policy) Window() time.Duration {
+	if p.WindowSeconds <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(p.WindowSeconds) * time.Second
+}
+
+func (p *Policy) Validate() error {
+	switch p.Mode {
+	case "", Never, OnFailure, Always:
+	default:
+		return errors.New(fmt.Sprintf("restart mode must be one of %s, %s, %s", Never, OnFailure, Always))
+	}
+	if p.InitialDelaySeconds < 0 || p.MaxDelaySeconds < 0 || p.MaxCrashes < 0 || p.WindowSeconds < 0 {
+		return errors.New("restart delays, max crashes and window can not be negative")
+	}
+	return nil
+}
+
+// ShouldRestart tells if a process which exited with the exit code needs to be started again
+func (p *Policy) ShouldRestart(exitCode int) bool {
+	switch p.mode() {
+	case Always:
+		return true
+	case OnFailure:
+		return exitCode != 0
+	default:
+		return false
+	}
+}
+
+// SystemdRestart maps the mode into the Restart= value of a unit
+func (p *Policy) SystemdRestart() string {
+	switch p.mode() {
+	case Always:
+		return "always"
+	case OnFailure:
+		return "on-failure"
+	default:
+		return "no"
+	}
+}
+
+// Backoff returns the delay before the nth restart, doubled on every crash up to the max delay
+func (p *Policy) Backoff(crashes int) time.Duration {
+	delay := p.InitialDelay()
+	for i := 1; i < crashes && delay < p.MaxDelay(); i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay() {
+		delay = p.MaxDelay()
+	}
+	return delay
+}
+
+// Scheduler keeps the recent crashes & the pending restart of each process
+type Scheduler struct {
+	mutex   sync.Mutex
+	crashes map[string][]time.Time
+	timers  map[string]*time.Timer
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		crashes: make(map[string][]time.Time),
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+// Schedule records the crash and runs restart after the backoff, it returns an error once the policy gives up
+func (inst *Scheduler) Schedule(name string, policy *Policy, restart func()) (time.Duration, error) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	now := time.Now()
+	crashes := make([]time.Time, 0, len(inst.crashes[name])+1)
+	for _, crashedAt := range inst.crashes[name] {
+		if now.Sub(crashedAt) < policy.Window() {
+			crashes = append(crashes, crashedAt)
+		}
+	}
+	crashes = append(crashes, now)
+	if timer, found := inst.timers[name]; found {
+		timer.Stop()
+		delete(inst.timers, name)
+	}
+	if len(crashes) > policy.Crashes() {
+		delete(inst.crashes, name)
+		return 0, errors.New(fmt.Sprintf("gave up after %d crashes in %s", len(crashes), policy.Window()))
+	}
+	inst.crashes[name] = crashes
+	delay := policy.Backoff(len(crashes))
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		inst.mutex.Lock()
+		current := inst.timers[name] == timer
+		if current {
+			delete(inst.timers, name)
+		}
+		inst.mutex.Unlock()
+		if current {
+			restart()
+		}
+	})
+	inst.timers[name] = timer
+	return delay, nil
+}
+
+// Pending tells if a restart is scheduled
+func (inst *Scheduler) Pending(name string) bool {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	_, found := inst.timers[name]
+	return found
+}
+
+// Reset cancels the pending restart and forgets the crashes, used on a manual start or stop
+func (inst *Scheduler) Reset(name string) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	if timer, found := inst.timers[name]; found {
+		timer.Stop()
+		delete(inst.timers, name)
+	}
+	delete(inst.crashes, name)
+}
@@ -0,0 +1,36 @@
+package restart
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := &Policy{InitialDelaySeconds: 1, MaxDelaySeconds: 5}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if got := policy.Backoff(i + 1); got != delay {
+			t.Fatalf("crash %d: expected %s, got %s", i+1, delay, got)
+		}
+	}
+}
+
+func TestScheduleGivesUp(t *testing.T) {
+	scheduler := NewScheduler()
+	policy := &Policy{Mode: OnFailure, InitialDelaySeconds: 60, MaxCrashes: 2}
+	restarts := 0
+	for i := 0; i < 2; i++ {
+		if _, err := scheduler.Schedule("demo", policy, func() { restarts++ }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !scheduler.Pending("demo") {
+		t.Fatal("expected a pending restart")
+	}
+	if _, err := scheduler.Schedule("demo", policy, func() { restarts++ }); err == nil {
+		t.Fatal("expected to give up after the third crash")
+	}
+	if scheduler.Pending("demo") || restarts != 0 {
+		t.Fatal("expected the pending restart to be cancelled")
+	}
+}
@@ -0,0 +1,150 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrForbidden is returned for paths outside the roots, or for changes under a read-only root
+var ErrForbidden = errors.New("forbidden")
+
+type Access int
+
+const (
+	Read   Access = iota
+	Write         // create or modify, symlinks are followed so a link can't point the write outside the roots
+	Remove        // delete, rename or unlink the entry itself, the last element isn't followed and the roots can't be removed
+)
+
+// Sandbox restricts the file APIs to Roots, ReadOnlyRoots can only be read and Denied can't be touched at all
+type Sandbox struct {
+	Roots         []string
+	ReadOnlyRoots []string
+	Denied        []string
+}
+
+// New canonicalizes the roots, the ones which don't exist yet are kept as absolute paths
+func New(roots, readOnlyRoots []string) (*Sandbox, error) {
+	s := &Sandbox{}
+	for _, root := range roots {
+		canonical, err := canonicalize(root, true)
+		if err != nil {
+			return nil, err
+		}
+		s.Roots = append(s.Roots, canonical)
+	}
+	for _, root := range readOnlyRoots {
+		canonical, err := canonicalize(root, true)
+		if err != nil {
+			return nil, err
+		}
+		s.ReadOnlyRoots = append(s.ReadOnlyRoots, canonical)
+	}
+	return s, nil
+}
+
+// Deny hides paths inside the roots, e.g. the keys of the platform
+func (s *Sandbox) Deny(paths ...string) error {
+	for _, p := range paths {
+		canonical, err := canonicalize(p, true)
+		if err != nil {
+			return err
+		}
+		s.Denied = append(s.Denied, canonical)
+	}
+	return nil
+}
+
+// Resolve returns the canonical path with the symlinks resolved, as long as it's allowed for the access
+func (s *Sandbox) Resolve(p string, access Access) (string, error) {
+	if p == "" {
+		return "", errors.New("path can not be empty")
+	}
+	canonical, err := canonicalize(p, access != Remove)
+	if err != nil {
+		return "", err
+	}
+	if within(canonical, s.Denied) != "" {
+		return "", fmt.Errorf("%w: %s is not accessible", ErrForbidden, p)
+	}
+	root, readOnlyRoot := within(canonical, s.Roots), within(canonical, s.ReadOnlyRoots)
+	if access == Read && readOnlyRoot != "" {
+		return canonical, nil
+	}
+	// the most specific root wins, e.g. a read-only config dir inside the data dir
+	if readOnlyRoot != "" && len(readOnlyRoot) >= len(root) {
+		return "", fmt.Errorf("%w: %s is read-only", ErrForbidden, p)
+	}
+	if root == "" {
+		return "", fmt.Errorf("%w: %s is outside of the allowed directories", ErrForbidden, p)
+	}
+	if access == Remove && canonical == root {
+		return "", fmt.Errorf("%w: %s is an allowed root and can not be removed", ErrForbidden, p)
+	}
+	return canonical, nil
+}
+
+// ResolveTree is Resolve for the operations on a whole tree (copy, move, delete), the tree can't contain a denied path either
+func (s *Sandbox) ResolveTree(p string, access Access) (string, error) {
+	canonical, err := s.Resolve(p, access)
+	if err != nil {
+		return "", err
+	}
+	for _, denied := range s.Denied {
+		if within(denied, []string{canonical}) != "" {
+			return "", fmt.Errorf("%w: %s contains %s which is not accessible", ErrForbidden, p, denied)
+		}
+	}
+	return canonical, nil
+}
+
+// Hidden tells if the walks of the file APIs need to leave the path out
+func (s *Sandbox) Hidden(p string) bool {
+	_, err := s.Resolve(p, Read)
+	return err != nil
+}
+
+// canonicalize makes the path absolute & clean, and resolves the symlinks of the part which exists
+func canonicalize(p string, followLast bool) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	dir, last := abs, ""
+	if !followLast && abs != "/" {
+		dir, last = filepath.Dir(abs), filepath.Base(abs)
+	}
+	// walk up to the first ancestor which exists, the rest can't contain links
+	missing := make([]string, 0)
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			dir = resolved
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		missing = append([]string{filepath.Base(dir)}, missing...)
+		dir = parent
+	}
+	return filepath.Join(append(append([]string{dir}, missing...), last)...), nil
+}
+
+// within returns the most specific root containing the path
+func within(p string, roots []string) string {
+	found := ""
+	for _, root := range roots {
+		if (p == root || strings.HasPrefix(p, strings.TrimSuffix(root, "/")+"/")) && len(root) > len(found) {
+			found = root
+		}
+	}
+	return found
+}
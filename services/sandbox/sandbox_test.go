@@ -0,0 +1,97 @@
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	root := path.Join(dir, "data")
+	readOnly := path.Join(root, "config")
+	outside := path.Join(dir, "etc")
+	for _, d := range []string{root, readOnly, outside} { // the read-only dir is nested in the writable one
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, path.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New([]string{root}, []string{readOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Deny(path.Join(root, "keys")); err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := []struct {
+		path   string
+		access Access
+	}{
+		{path.Join(root, "new", "file.txt"), Write},
+		{path.Join(readOnly, "config.yml"), Read},
+		{path.Join(root, "escape"), Remove}, // the link itself
+	}
+	for _, c := range allowed {
+		if _, err = s.Resolve(c.path, c.access); err != nil {
+			t.Errorf("%s: %s", c.path, err)
+		}
+	}
+
+	forbidden := []struct {
+		path   string
+		access Access
+	}{
+		{outside, Read},
+		{path.Join(root, "..", "etc", "passwd"), Read},
+		{path.Join(root, "escape", "passwd"), Write},
+		{path.Join(root, "escape"), Read},
+		{path.Join(readOnly, "config.yml"), Write},
+		{root, Remove},
+		{path.Join(root, "keys", "secrets.key"), Read},
+	}
+	for _, c := range forbidden {
+		if _, err = s.Resolve(c.path, c.access); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s: expected forbidden, got %v", c.path, err)
+		}
+	}
+}
+
+func TestResolveTree(t *testing.T) {
+	root := t.TempDir()
+	keys := path.Join(root, "keys")
+	secrets := path.Join(root, "hosts", "secrets")
+	for _, d := range []string{keys, secrets, path.Join(root, "hosts", "logs")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := New([]string{root}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Deny(keys, secrets); err != nil {
+		t.Fatal(err)
+	}
+	// the parents of the denied paths can be listed, but not copied, moved or deleted as a whole
+	for _, p := range []string{path.Join(root, "hosts"), path.Join(root, "hosts", "..", "hosts")} {
+		if _, err = s.Resolve(p, Read); err != nil {
+			t.Errorf("%s: %s", p, err)
+		}
+		for _, access := range []Access{Read, Remove} {
+			if _, err = s.ResolveTree(p, access); !errors.Is(err, ErrForbidden) {
+				t.Errorf("%s: expected forbidden, got %v", p, err)
+			}
+		}
+	}
+	if _, err = s.ResolveTree(path.Join(root, "hosts", "logs"), Remove); err != nil {
+		t.Error(err)
+	}
+	if !s.Hidden(path.Join(secrets, "a.env")) || s.Hidden(path.Join(root, "hosts", "logs")) {
+		t.Error("only the denied paths are hidden from the walks")
+	}
+}
@@ -0,0 +1,190 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/services/filelist"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultMaxResults  = 1000
+	DefaultMaxFileSize = 10 * 1024 * 1024
+	MaxContext         = 10
+	maxLineSize        = 1024 * 1024
+	sniffSize          = 8000
+)
+
+// ErrMaxResults stops the walk once enough lines matched
+var ErrMaxResults = errors.New("max results reached")
+
+type Options struct {
+	Pattern     string
+	Regex       bool
+	IgnoreCase  bool
+	Glob        string // matched against the file name
+	MaxDepth    int
+	MaxResults  int
+	Context     int   // lines before & after each match
+	MaxFileSize int64 // bigger files are skipped
+	Skip        func(p string) bool
+}
+
+type Match struct {
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+type Summary struct {
+	Done      bool `json:"done"`
+	Files     int  `json:"files"`   // searched
+	Skipped   int  `json:"skipped"` // binary, too big or unreadable
+	Matches   int  `json:"matches"`
+	Truncated bool `json:"truncated"`
+}
+
+func (o *Options) matcher() (func(line string) bool, error) {
+	if o.Pattern == "" {
+		return nil, errors.New("pattern can not be empty")
+	}
+	if o.Context < 0 || o.Context > MaxContext {
+		return nil, errors.New(fmt.Sprintf("context needs to be between 0 and %d", MaxContext))
+	}
+	if o.MaxResults <= 0 {
+		o.MaxResults = DefaultMaxResults
+	}
+	if o.MaxFileSize <= 0 {
+		o.MaxFileSize = DefaultMaxFileSize
+	}
+	if !o.Regex {
+		if o.IgnoreCase {
+			pattern := strings.ToLower(o.Pattern)
+			return func(line string) bool { return strings.Contains(strings.ToLower(line), pattern) }, nil
+		}
+		return func(line string) bool { return strings.Contains(line, o.Pattern) }, nil
+	}
+	pattern := o.Pattern
+	if o.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid regex %s: %s", o.Pattern, err.Error()))
+	}
+	return re.MatchString, nil
+}
+
+// Search walks the tree like the files walk API and calls emit for every matching line, in file & line order
+func Search(root string, o *Options, emit func(m *Match) error) (*Summary, error) {
+	match, err := o.matcher()
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	walk := &filelist.Options{Glob: o.Glob, MaxDepth: o.MaxDepth, Type: filelist.TypeFile, Skip: o.Skip}
+	err = filelist.Each(root, walk, func(p string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > o.MaxFileSize {
+			summary.Skipped++
+			return nil
+		}
+		searched, err := searchFile(p, o, match, summary, emit)
+		if searched {
+			summary.Files++
+		} else {
+			summary.Skipped++
+		}
+		return err
+	})
+	if errors.Is(err, ErrMaxResults) {
+		summary.Truncated = true
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary.Done = true
+	return summary, nil
+}
+
+func isBinary(r *bufio.Reader) bool {
+	head, _ := r.Peek(sniffSize)
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// searchFile returns false when the file is skipped, the after lines of a match are collected before it is emitted
+func searchFile(p string, o *Options, match func(string) bool, summary *Summary, emit func(m *Match) error) (bool, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return false, nil
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	if isBinary(reader) {
+		return false, nil
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	var before []string
+	var pending []*Match
+	flush := func(all bool) error {
+		for len(pending) > 0 && (all || len(pending[0].After) >= o.Context) {
+			if err := emit(pending[0]); err != nil {
+				return err
+			}
+			pending = pending[1:]
+		}
+		return nil
+	}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		for _, m := range pending {
+			if len(m.After) < o.Context {
+				m.After = append(m.After, line)
+			}
+		}
+		if err = flush(false); err != nil {
+			return true, err
+		}
+		if summary.Matches < o.MaxResults && match(line) {
+			summary.Matches++
+			m := &Match{File: p, Line: lineNumber, Text: line}
+			if len(before) > 0 {
+				m.Before = append([]string{}, before...)
+			}
+			pending = append(pending, m)
+			if err = flush(false); err != nil {
+				return true, err
+			}
+		}
+		if o.Context > 0 {
+			if before = append(before, line); len(before) > o.Context {
+				before = before[1:]
+			}
+		}
+		if summary.Matches >= o.MaxResults && len(pending) == 0 {
+			return true, ErrMaxResults
+		}
+	}
+	if err = flush(true); err != nil {
+		return true, err
+	}
+	if err = scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return false, nil // a line longer than maxLineSize, most likely not a text file
+	}
+	if summary.Matches >= o.MaxResults {
+		return true, ErrMaxResults
+	}
+	return true, nil
+}
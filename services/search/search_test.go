@@ -0,0 +1,59 @@
+package search
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a/app.yml":  "name: a\nhost: 10.0.0.1\nport: 1660\n",
+		"b/app.yml":  "host: 10.0.0.1\n",
+		"b/app.log":  "connected to 10.0.0.1\n",
+		"c/data.bin": "10.0.0.1\x00\x01",
+	}
+	for name, content := range files {
+		p := path.Join(root, name)
+		_ = os.MkdirAll(path.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var matches []*Match
+	summary, err := Search(root, &Options{Pattern: "10.0.0.1", Glob: "*.yml", Context: 1}, func(m *Match) error {
+		matches = append(matches, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || summary.Files != 2 || summary.Truncated {
+		t.Fatalf("unexpected result %+v %+v", summary, matches)
+	}
+	first := matches[0]
+	if first.Line != 2 || first.Before[0] != "name: a" || first.After[0] != "port: 1660" {
+		t.Fatalf("unexpected context %+v", first)
+	}
+
+	matches = nil
+	summary, err = Search(root, &Options{Pattern: `10\.0\.0\.\d+`, Regex: true, MaxResults: 2}, func(m *Match) error {
+		matches = append(matches, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || !summary.Truncated {
+		t.Fatalf("expected the results to be truncated %+v", summary)
+	}
+
+	summary, err = Search(root, &Options{Pattern: "10.0.0.1", Glob: "*.bin"}, func(m *Match) error {
+		t.Fatalf("binary file matched %+v", m)
+		return nil
+	})
+	if err != nil || summary.Skipped != 1 {
+		t.Fatalf("expected the binary file to be skipped %+v %v", summary, err)
+	}
+}
@@ -0,0 +1,142 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the secret values in the API responses, sending it back keeps the stored value
+const Redacted = "******"
+
+const prefix = "enc:v1:"
+
+// Box encrypts with AES-256-GCM, the key is created under KeyPath on first use and never leaves the device
+type Box struct {
+	KeyPath string
+	mutex   sync.Mutex
+	aead    cipher.AEAD
+}
+
+func New(keyPath string) *Box {
+	return &Box{KeyPath: keyPath}
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func (inst *Box) Encrypt(plain string) (string, error) {
+	aead, err := inst.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (inst *Box) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+	aead, err := inst.cipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("failed to decrypt, was %s replaced? %s", inst.KeyPath, err.Error()))
+	}
+	return string(plain), nil
+}
+
+// DecryptEnv decrypts the encrypted values of the KEY=value pairs, the plain ones are kept as they are
+func (inst *Box) DecryptEnv(env []string) ([]string, error) {
+	decrypted := make([]string, 0, len(env))
+	for _, e := range env {
+		key, value, _ := strings.Cut(e, "=")
+		if IsEncrypted(value) {
+			plain, err := inst.Decrypt(value)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("%s: %s", key, err.Error()))
+			}
+			e = key + "=" + plain
+		}
+		decrypted = append(decrypted, e)
+	}
+	return decrypted, nil
+}
+
+func (inst *Box) cipher() (cipher.AEAD, error) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	if inst.aead != nil {
+		return inst.aead, nil
+	}
+	key, err := inst.loadKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	inst.aead, err = cipher.NewGCM(block)
+	return inst.aead, err
+}
+
+func (inst *Box) loadKey() ([]byte, error) {
+	key, err := os.ReadFile(inst.KeyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, errors.New(fmt.Sprintf("invalid key in %s", inst.KeyPath))
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key = make([]byte, 32)
+	if _, err = io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(path.Dir(inst.KeyPath), 0700); err != nil {
+		return nil, err
+	}
+	// O_EXCL so that two processes never end up with different keys
+	f, err := os.OpenFile(inst.KeyPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return os.ReadFile(inst.KeyPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Write(key); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return key, f.Close()
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"path"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyPath := path.Join(t.TempDir(), "keys", "secrets.key")
+	box := New(keyPath)
+	encrypted, err := box.Encrypt("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Fatalf("expected an encrypted value, got %s", encrypted)
+	}
+	plain, err := New(keyPath).Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain != "s3cret" {
+		t.Fatalf("expected s3cret, got %s", plain)
+	}
+	if _, err = New(path.Join(t.TempDir(), "other.key")).Decrypt(encrypted); err == nil {
+		t.Fatal("expected another key to fail")
+	}
+}
+
+func TestDecryptEnv(t *testing.T) {
+	box := New(path.Join(t.TempDir(), "secrets.key"))
+	encrypted, err := box.Encrypt("a=b c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := box.DecryptEnv([]string{"PLAIN=1", "TOKEN=" + encrypted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 2 || env[0] != "PLAIN=1" || env[1] != "TOKEN=a=b c" {
+		t.Fatalf("unexpected env %v", env)
+	}
+	if _, err = box.DecryptEnv([]string{"TOKEN=enc:v1:garbage"}); err == nil {
+		t.Fatal("expected an invalid value to fail")
+	}
+}
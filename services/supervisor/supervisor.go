@@ -0,0 +1,239 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	StateRunning = "running"
+	StateStopped = "stopped"
+	StateExited  = "exited"
+)
+
+// Spec describes a process to be supervised, Stdout & Stderr are closed once the process exits if they are io.Closer
+type Spec struct {
+	Name    string
+	Command string
+	Args    []string
+	Dir     string
+	Env     []string
+	Stdout  io.Writer
+	Stderr  io.Writer
+	OnExit  func(status *Status) // called once the process is gone and before Stop returns, the state is stopped when it was stopped by Stop
+	// Cgroup is a cgroup v2 directory the process is started in, so that its children can't escape the limits,
+	// it's closed once Start returns
+	Cgroup *os.File
+}
+
+type Status struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	ExitedAt  *time.Time `json:"exitedAt,omitempty"`
+	ExitCode  *int       `json:"exitCode,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+type process struct {
+	spec      *Spec
+	cmd       *exec.Cmd
+	startedAt time.Time
+	exitedAt  time.Time
+	exitCode  int
+	err       error
+	stopped   bool
+	done      chan struct{}
+}
+
+type Supervisor struct {
+	StopTimeout time.Duration // time between SIGTERM and SIGKILL
+	mutex       sync.Mutex
+	processes   map[string]*process
+}
+
+func New(stopTimeout time.Duration) *Supervisor {
+	if stopTimeout <= 0 {
+		stopTimeout = 10 * time.Second
+	}
+	return &Supervisor{
+		StopTimeout: stopTimeout,
+		processes:   make(map[string]*process),
+	}
+}
+
+func (inst *Supervisor) Start(spec *Spec) error {
+	if spec == nil || spec.Name == "" {
+		return errors.New("process name can not be empty")
+	}
+	if spec.Cgroup != nil {
+		defer spec.Cgroup.Close()
+	}
+	if spec.Command == "" {
+		return errors.New(fmt.Sprintf("command can not be empty for %s", spec.Name))
+	}
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	if p, found := inst.processes[spec.Name]; found && p.running() {
+		return errors.New(fmt.Sprintf("%s is already running with pid %d", spec.Name, p.cmd.Process.Pid))
+	}
+
+	cmd := exec.Command(spec.Command, spec.Args...)
+	cmd.Dir = spec.Dir
+	cmd.Env = append(os.Environ(), spec.Env...)
+	cmd.Stdout = spec.Stdout
+	cmd.Stderr = spec.Stderr
+	// own process group, so that children get the signals as well
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if spec.Cgroup != nil {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(spec.Cgroup.Fd())
+	}
+	if err := cmd.Start(); err != nil {
+		closeOutputs(spec)
+		return err
+	}
+	p := &process{
+		spec:      spec,
+		cmd:       cmd,
+		startedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	inst.processes[spec.Name] = p
+	go func() {
+		p.wait()
+		if spec.OnExit != nil {
+			inst.mutex.Lock()
+			status := p.exitStatus()
+			inst.mutex.Unlock()
+			spec.OnExit(status)
+		}
+		close(p.done)
+	}()
+	return nil
+}
+
+// Stop sends SIGTERM to the process group and SIGKILL once StopTimeout is elapsed
+func (inst *Supervisor) Stop(name string) error {
+	inst.mutex.Lock()
+	p, found := inst.processes[name]
+	if !found || !p.running() {
+		inst.mutex.Unlock()
+		return nil
+	}
+	p.stopped = true
+	inst.mutex.Unlock()
+	pid := p.cmd.Process.Pid
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(inst.StopTimeout):
+	}
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	<-p.done
+	return nil
+}
+
+// Remove stops the process and forgets about it
+func (inst *Supervisor) Remove(name string) error {
+	if err := inst.Stop(name); err != nil {
+		return err
+	}
+	inst.mutex.Lock()
+	delete(inst.processes, name)
+	inst.mutex.Unlock()
+	return nil
+}
+
+func (inst *Supervisor) IsRunning(name string) bool {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	p, found := inst.processes[name]
+	return found && p.running()
+}
+
+func (inst *Supervisor) Status(name string) *Status {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	p, found := inst.processes[name]
+	if !found {
+		return &Status{Name: name, State: StateStopped}
+	}
+	return p.status()
+}
+
+func (p *process) wait() {
+	err := p.cmd.Wait()
+	p.exitedAt = time.Now()
+	p.exitCode = p.cmd.ProcessState.ExitCode()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		p.err = err
+	}
+	closeOutputs(p.spec)
+}
+
+func (p *process) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *process) status() *Status {
+	if p.running() {
+		startedAt := p.startedAt
+		return &Status{
+			Name:      p.spec.Name,
+			State:     StateRunning,
+			PID:       p.cmd.Process.Pid,
+			StartedAt: &startedAt,
+		}
+	}
+	return p.exitStatus()
+}
+
+func (p *process) exitStatus() *Status {
+	startedAt := p.startedAt
+	status := &Status{
+		Name:      p.spec.Name,
+		StartedAt: &startedAt,
+	}
+	exitedAt := p.exitedAt
+	exitCode := p.exitCode
+	status.ExitedAt = &exitedAt
+	status.ExitCode = &exitCode
+	status.State = StateExited
+	if p.stopped {
+		status.State = StateStopped
+	}
+	if p.err != nil {
+		status.Error = p.err.Error()
+	}
+	return status
+}
+
+func closeOutputs(spec *Spec) {
+	if c, ok := spec.Stdout.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if spec.Stderr != spec.Stdout {
+		if c, ok := spec.Stderr.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package supervisor
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func TestStartStop(t *testing.T) {
+	s := New(500 * time.Millisecond)
+	out := &syncBuffer{}
+	err := s.Start(&Spec{
+		Name:    "test",
+		Command: "sh",
+		Args:    []string{"-c", "echo $GREETING; sleep 30"},
+		Env:     []string{"GREETING=hello"},
+		Stdout:  out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := s.Status("test"); status.State != StateRunning || status.PID == 0 {
+		t.Fatalf("expected running process, got %+v", status)
+	}
+	if err = s.Start(&Spec{Name: "test", Command: "true"}); err == nil {
+		t.Fatal("expected an error when starting a running process")
+	}
+	for i := 0; i < 50 && out.String() == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err = s.Stop("test"); err != nil {
+		t.Fatal(err)
+	}
+	if status := s.Status("test"); status.State != StateStopped {
+		t.Fatalf("expected stopped process, got %+v", status)
+	}
+	if out.String() != "hello\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestStopKillsAfterTimeout(t *testing.T) {
+	s := New(200 * time.Millisecond)
+	err := s.Start(&Spec{Name: "stubborn", Command: "sh", Args: []string{"-c", "trap '' TERM; sleep 30"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // let the trap get installed
+	start := time.Now()
+	if err = s.Stop("stubborn"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 200*time.Millisecond {
+		t.Fatal("expected the process to survive SIGTERM")
+	}
+	if s.IsRunning("stubborn") {
+		t.Fatal("expected the process to be killed")
+	}
+}
@@ -0,0 +1,99 @@
+package systeminfo
+
+import (
+	"github.com/shirou/gopsutil/process"
+	"math"
+	"time"
+)
+
+// ProcessTreeStats sums the stats of a process and all its descendants
+type ProcessTreeStats struct {
+	PID           int32     `json:"pid"`
+	Processes     int       `json:"processes"`
+	CPUPercentage float64   `json:"cpuPercentage"` // over the sampling interval, 100 is one full core
+	RSS           uint64    `json:"rss"`
+	Memory        string    `json:"memory"`
+	Threads       int32     `json:"threads"`
+	OpenFDs       int32     `json:"openFDs"`
+	StartedAt     time.Time `json:"startedAt"`
+	Uptime        int64     `json:"uptime"` // seconds
+}
+
+// GetProcessTreeStats samples the cpu times of all the trees at once, so the interval is only waited for once
+func (s *unixSystem) GetProcessTreeStats(pids []int32, interval time.Duration) ([]*ProcessTreeStats, error) {
+	processes, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int32][]*process.Process)
+	byPID := make(map[int32]*process.Process)
+	for _, p := range processes {
+		byPID[p.Pid] = p
+		if ppid, err := p.Ppid(); err == nil {
+			children[ppid] = append(children[ppid], p)
+		}
+	}
+
+	trees := make([][]*process.Process, len(pids))
+	before := make([]float64, len(pids))
+	for i, pid := range pids {
+		root, found := byPID[pid]
+		if pid <= 0 || !found {
+			continue
+		}
+		trees[i] = processTree(root, children)
+		before[i] = cpuSeconds(trees[i])
+	}
+	if interval > 0 {
+		time.Sleep(interval)
+	}
+
+	stats := make([]*ProcessTreeStats, len(pids))
+	for i, tree := range trees {
+		if tree == nil {
+			continue
+		}
+		stat := &ProcessTreeStats{PID: pids[i], Processes: len(tree)}
+		if createTime, err := tree[0].CreateTime(); err == nil {
+			stat.StartedAt = time.UnixMilli(createTime)
+			stat.Uptime = int64(time.Since(stat.StartedAt).Seconds())
+		}
+		if interval > 0 {
+			// children exiting in between take their cpu time with them
+			stat.CPUPercentage = math.Max(0, (cpuSeconds(tree)-before[i])/interval.Seconds()*100)
+		}
+		for _, p := range tree {
+			if memInfo, err := p.MemoryInfo(); err == nil {
+				stat.RSS += memInfo.RSS
+			}
+			if threads, err := p.NumThreads(); err == nil {
+				stat.Threads += threads
+			}
+			if fds, err := p.NumFDs(); err == nil {
+				stat.OpenFDs += fds
+			}
+		}
+		stat.Memory = prettyByteSize(int(stat.RSS))
+		stats[i] = stat
+	}
+	return stats, nil
+}
+
+func processTree(root *process.Process, children map[int32][]*process.Process) []*process.Process {
+	tree := []*process.Process{root}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i].Pid]...)
+	}
+	return tree
+}
+
+// cpuSeconds is the user & system time of the processes, the ones which exited in between are skipped
+func cpuSeconds(processes []*process.Process) float64 {
+	total := 0.0
+	for _, p := range processes {
+		if times, err := p.Times(); err == nil {
+			total += times.User + times.System
+		}
+	}
+	return total
+}
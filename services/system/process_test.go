@@ -0,0 +1,75 @@
+package systeminfo
+
+import (
+	"github.com/shirou/gopsutil/process"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestProcessTree(t *testing.T) {
+	p := func(pid int32) *process.Process {
+		return &process.Process{Pid: pid}
+	}
+	children := map[int32][]*process.Process{
+		1: {p(2), p(3)},
+		2: {p(4)},
+		4: {p(5)},
+		9: {p(10)},
+	}
+	tree := processTree(p(1), children)
+	pids := make([]int32, 0, len(tree))
+	for _, process := range tree {
+		pids = append(pids, process.Pid)
+	}
+	if !reflect.DeepEqual(pids, []int32{1, 2, 3, 4, 5}) {
+		t.Fatalf("expected the root and all its descendants, got %v", pids)
+	}
+}
+
+// startTree starts a shell with two children, the shell spins so that the tree uses some cpu
+func startTree(t *testing.T) int32 {
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30 & while :; do :; done")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command("pkill", "-P", strconv.Itoa(cmd.Process.Pid)).Run()
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	// wait for the children to be forked
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if p, err := process.NewProcess(int32(cmd.Process.Pid)); err == nil {
+			if children, _ := p.Children(); len(children) == 2 {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return int32(cmd.Process.Pid)
+}
+
+func TestGetProcessTreeStats(t *testing.T) {
+	pid := startTree(t)
+	stats, err := New().GetProcessTreeStats([]int32{pid, 0, 1 << 30}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 || stats[1] != nil || stats[2] != nil {
+		t.Fatalf("expected stats for the running tree only, got %v", stats)
+	}
+	stat := stats[0]
+	if stat.PID != pid || stat.Processes != 3 {
+		t.Fatalf("expected the shell and its 2 children, got %+v", stat)
+	}
+	if stat.RSS == 0 || stat.Memory == "" || stat.Threads < 3 || stat.OpenFDs < 3 {
+		t.Fatalf("expected memory, threads and fds to be summed over the tree, got %+v", stat)
+	}
+	if stat.CPUPercentage <= 0 || stat.StartedAt.IsZero() || stat.StartedAt.After(time.Now()) {
+		t.Fatalf("expected cpu usage and a start time, got %+v", stat)
+	}
+}
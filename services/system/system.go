@@ -30,6 +30,7 @@ type System interface {
 	GetMemoryFree() string
 	GetTopProcessesByCPUUsage(count int) ([]*topProcess, error)
 	GetTopProcessesByMemory(count int) ([]*topProcess, error)
+	GetProcessTreeStats(pids []int32, interval time.Duration) ([]*ProcessTreeStats, error)
 	GetHostUniqueID() (string, error) // try mac or system uuid
 	ExecuteMethods(methods []string) (map[string]interface{}, error)
 }
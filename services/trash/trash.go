@@ -0,0 +1,284 @@
+package trash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/lib-files/fileutils"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("trash item not found")
+	ErrExists   = errors.New("restore destination already exists")
+	ErrTooBig   = errors.New("too big for the trash")
+)
+
+const (
+	metaFile = "item.json"
+	dataDir  = "data"
+)
+
+// Item is a deleted file or dir, kept in <Dir>/<id>/data/<name> until it's restored, purged or expired
+type Item struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"originalPath"`
+	IsDir        bool      `json:"isDir"`
+	Size         int64     `json:"size"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	DeletedBy    string    `json:"deletedBy"`
+}
+
+type Manager struct {
+	Enabled  bool // when disabled the deletes are permanent, the existing items can still be restored
+	Dir      string
+	MaxAge   time.Duration // 0 keeps the items forever
+	MaxBytes int64         // the oldest items are purged first once the trash is bigger, 0 is unlimited
+	FileMode os.FileMode
+	mutex    sync.Mutex
+}
+
+func New(dir string, maxAge time.Duration, maxBytes int64) *Manager {
+	return &Manager{Enabled: true, Dir: dir, MaxAge: maxAge, MaxBytes: maxBytes, FileMode: 0755}
+}
+
+func within(p, dir string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// Move puts the file or dir into the trash, it's copied when the trash is on another filesystem.
+// A path bigger than MaxBytes is refused with ErrTooBig, since the expiry would purge it right away
+func (inst *Manager) Move(p, deletedBy string) (*Item, error) {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return nil, err
+	}
+	if within(inst.Dir, p) {
+		return nil, errors.New(fmt.Sprintf("%s contains the trash, it can't be moved into it", p))
+	}
+	size := diskSize(p)
+	if inst.MaxBytes > 0 && size > inst.MaxBytes {
+		return nil, fmt.Errorf("%w: %s is %d MB, the trash holds %d MB", ErrTooBig, p, size/1024/1024, inst.MaxBytes/1024/1024)
+	}
+	id := make([]byte, 8)
+	if _, err = rand.Read(id); err != nil {
+		return nil, err
+	}
+	item := &Item{
+		ID:           hex.EncodeToString(id),
+		Name:         filepath.Base(p),
+		OriginalPath: p,
+		IsDir:        info.IsDir(),
+		Size:         size,
+		DeletedAt:    time.Now().UTC(),
+		DeletedBy:    deletedBy,
+	}
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	if err = os.MkdirAll(path.Join(inst.itemDir(item.ID), dataDir), inst.FileMode); err != nil {
+		return nil, err
+	}
+	// the metadata goes first, a crash in between leaves an item which can still be restored or purged
+	if err = inst.save(item); err != nil {
+		_ = os.RemoveAll(inst.itemDir(item.ID))
+		return nil, err
+	}
+	if err = move(p, inst.dataPath(item)); err != nil {
+		_ = os.RemoveAll(inst.itemDir(item.ID))
+		return nil, err
+	}
+	go inst.Expire()
+	return item, nil
+}
+
+// List returns the items, the newest first
+func (inst *Manager) List() ([]*Item, error) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	return inst.list()
+}
+
+func (inst *Manager) Get(id string) (*Item, error) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	return inst.load(id)
+}
+
+// Restore moves the item back to its original path or to destination when set, an existing path is never overwritten
+func (inst *Manager) Restore(id, destination string) (*Item, error) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	item, err := inst.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if destination == "" {
+		destination = item.OriginalPath
+	}
+	if _, err = os.Lstat(destination); err == nil {
+		return item, ErrExists
+	}
+	if err = os.MkdirAll(filepath.Dir(destination), inst.FileMode); err != nil {
+		return item, err
+	}
+	if err = move(inst.dataPath(item), destination); err != nil {
+		return item, err
+	}
+	item.OriginalPath = destination
+	return item, os.RemoveAll(inst.itemDir(id))
+}
+
+func (inst *Manager) Purge(id string) error {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	if _, err := inst.load(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(inst.itemDir(id))
+}
+
+// PurgeAll empties the trash, it returns the number of purged items
+func (inst *Manager) PurgeAll() (int, error) {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	items, err := inst.list()
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if err = os.RemoveAll(inst.itemDir(item.ID)); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
+
+// Expire purges the items older than MaxAge, then the oldest ones until the trash fits into MaxBytes, the newest item is kept
+func (inst *Manager) Expire() {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	items, err := inst.list()
+	if err != nil {
+		return
+	}
+	var total int64
+	for _, item := range items {
+		total += item.Size
+	}
+	for i := len(items) - 1; i >= 0; i-- { // oldest first
+		item := items[i]
+		expired := inst.MaxAge > 0 && time.Since(item.DeletedAt) > inst.MaxAge
+		tooBig := i > 0 && inst.MaxBytes > 0 && total > inst.MaxBytes
+		if !expired && !tooBig {
+			continue
+		}
+		log.Infof("purging trash item %s (%s)", item.ID, item.OriginalPath)
+		if err = os.RemoveAll(inst.itemDir(item.ID)); err != nil {
+			log.Errorf("failed to purge trash item %s: %s", item.ID, err.Error())
+			continue
+		}
+		total -= item.Size
+	}
+}
+
+// RunExpiry calls Expire on every interval, it never returns
+func (inst *Manager) RunExpiry(interval time.Duration) {
+	for {
+		inst.Expire()
+		time.Sleep(interval)
+	}
+}
+
+func (inst *Manager) list() ([]*Item, error) {
+	entries, err := os.ReadDir(inst.Dir)
+	if os.IsNotExist(err) {
+		return make([]*Item, 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*Item, 0)
+	for _, entry := range entries {
+		item, err := inst.load(entry.Name())
+		if err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	return items, nil
+}
+
+func (inst *Manager) itemDir(id string) string {
+	return path.Join(inst.Dir, id)
+}
+
+func (inst *Manager) dataPath(item *Item) string {
+	return path.Join(inst.itemDir(item.ID), dataDir, item.Name)
+}
+
+func (inst *Manager) save(item *Item) error {
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(inst.itemDir(item.ID), metaFile), data, 0644)
+}
+
+func (inst *Manager) load(id string) (*Item, error) {
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(path.Join(inst.itemDir(id), metaFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	item := &Item{}
+	if err = json.Unmarshal(data, item); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid trash item %s: %s", id, err.Error()))
+	}
+	return item, nil
+}
+
+// move renames, falling back to copy & remove across filesystems
+func move(source, destination string) error {
+	err := os.Rename(source, destination)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err = fileutils.Copy(source, destination); err != nil {
+		_ = os.RemoveAll(destination)
+		return err
+	}
+	return os.RemoveAll(source)
+}
+
+func diskSize(p string) int64 {
+	var size int64
+	_ = filepath.WalkDir(p, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
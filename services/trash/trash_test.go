@@ -0,0 +1,96 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestMoveRestore(t *testing.T) {
+	root := t.TempDir()
+	m := New(path.Join(root, "trash"), time.Hour, 0)
+	dir := path.Join(root, "app", "data")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(path.Join(dir, "app.db"), []byte("data"), 0644)
+
+	item, err := m.Move(dir, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatal("expected the dir to be moved")
+	}
+	if item.Size != 4 || !item.IsDir || item.DeletedBy != "admin" {
+		t.Fatalf("unexpected item %+v", item)
+	}
+	items, err := m.List()
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected one item, got %v %v", items, err)
+	}
+
+	_ = os.MkdirAll(dir, 0755)
+	if _, err = m.Restore(item.ID, ""); !errors.Is(err, ErrExists) {
+		t.Fatalf("expected an existing destination error, got %v", err)
+	}
+	_ = os.Remove(dir)
+	if _, err = m.Restore(item.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path.Join(dir, "app.db")); string(data) != "data" {
+		t.Fatal("restored file doesn't match")
+	}
+	if _, err = m.Get(item.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected the item to be gone after the restore")
+	}
+	if _, err = m.Move(root, "admin"); err == nil {
+		t.Fatal("expected a dir containing the trash to be refused")
+	}
+}
+
+func TestExpire(t *testing.T) {
+	root := t.TempDir()
+	m := New(path.Join(root, "trash"), 0, 10)
+	for _, name := range []string{"a", "b", "c"} {
+		p := path.Join(root, name)
+		_ = os.WriteFile(p, []byte("12345"), 0644)
+		if _, err := m.Move(p, ""); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.Expire()
+	items, _ := m.List()
+	if len(items) != 2 || items[1].Name != "b" {
+		t.Fatalf("expected the oldest item to be purged, got %+v", items)
+	}
+	if n, err := m.PurgeAll(); err != nil || n != 2 {
+		t.Fatalf("expected 2 purged items, got %d %v", n, err)
+	}
+}
+
+func TestMoveTooBig(t *testing.T) {
+	root := t.TempDir()
+	m := New(path.Join(root, "trash"), 0, 10)
+	p := path.Join(root, "big")
+	_ = os.WriteFile(p, []byte("12345678901"), 0644)
+	if _, err := m.Move(p, ""); !errors.Is(err, ErrTooBig) {
+		t.Fatalf("expected ErrTooBig, got %v", err)
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Fatal("a refused path needs to stay where it is")
+	}
+	// a smaller cap later on doesn't purge the newest item
+	_ = os.WriteFile(p, []byte("12345"), 0644)
+	if _, err := m.Move(p, ""); err != nil {
+		t.Fatal(err)
+	}
+	m.MaxBytes = 1
+	m.Expire()
+	if items, _ := m.List(); len(items) != 1 {
+		t.Fatalf("expected the newest item to be kept, got %+v", items)
+	}
+}
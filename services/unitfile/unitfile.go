@@ -0,0 +1,193 @@
+package unitfile
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NubeIO/lib-systemctl-go/systemctl"
+	"github.com/NubeIO/platform/constants"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"unicode"
+)
+
+type Unit struct {
+	Description      string
+	WorkingDirectory string
+	ExecStart        []string
+	Environment      []string
+	EnvironmentFile  string
+	Restart          string // always, on-failure, no
+	RestartSec       int
+	SyslogIdentifier string
+
+	StartLimitIntervalSec int // systemd defaults apply when 0
+	StartLimitBurst       int
+
+	MemoryMaxMB     int // the resource limits are left out when 0
+	CPUQuotaPercent int
+	Nice            int
+	LimitNOFILE     uint64
+}
+
+// Render returns the content of the .service file
+func (u *Unit) Render() string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString(fmt.Sprintf("Description=%s\n", escapeSpecifiers(u.Description)))
+	b.WriteString("After=network.target\n")
+	if u.StartLimitIntervalSec > 0 {
+		b.WriteString(fmt.Sprintf("StartLimitIntervalSec=%d\n", u.StartLimitIntervalSec))
+	}
+	if u.StartLimitBurst > 0 {
+		b.WriteString(fmt.Sprintf("StartLimitBurst=%d\n", u.StartLimitBurst))
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	b.WriteString("User=root\n")
+	if u.WorkingDirectory != "" {
+		b.WriteString(fmt.Sprintf("WorkingDirectory=%s\n", escapeSpecifiers(u.WorkingDirectory)))
+	}
+	for _, env := range u.Environment {
+		b.WriteString(fmt.Sprintf("Environment=%s\n", quote(escapeSpecifiers(env))))
+	}
+	if u.EnvironmentFile != "" {
+		b.WriteString(fmt.Sprintf("EnvironmentFile=%s\n", u.EnvironmentFile))
+	}
+	args := make([]string, 0, len(u.ExecStart))
+	for _, arg := range u.ExecStart {
+		// $ would expand an env var of the unit
+		args = append(args, quote(strings.ReplaceAll(escapeSpecifiers(arg), "$", "$$")))
+	}
+	b.WriteString(fmt.Sprintf("ExecStart=%s\n", strings.Join(args, " ")))
+	restart := u.Restart
+	if restart == "" {
+		restart = "always"
+	}
+	b.WriteString(fmt.Sprintf("Restart=%s\n", restart))
+	restartSec := u.RestartSec
+	if restartSec == 0 {
+		restartSec = 10
+	}
+	b.WriteString(fmt.Sprintf("RestartSec=%d\n", restartSec))
+	if u.MemoryMaxMB > 0 {
+		b.WriteString(fmt.Sprintf("MemoryMax=%dM\n", u.MemoryMaxMB))
+	}
+	if u.CPUQuotaPercent > 0 {
+		b.WriteString(fmt.Sprintf("CPUQuota=%d%%\n", u.CPUQuotaPercent))
+	}
+	if u.Nice != 0 {
+		b.WriteString(fmt.Sprintf("Nice=%d\n", u.Nice))
+	}
+	if u.LimitNOFILE > 0 {
+		b.WriteString(fmt.Sprintf("LimitNOFILE=%d\n", u.LimitNOFILE))
+	}
+	b.WriteString("StandardOutput=journal\n")
+	b.WriteString("StandardError=journal\n")
+	if u.SyslogIdentifier != "" {
+		b.WriteString(fmt.Sprintf("SyslogIdentifier=%s\n", u.SyslogIdentifier))
+	}
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// escapeSpecifiers keeps systemd from expanding %n, %h, ... in the value
+func escapeSpecifiers(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+// quote wraps the value in double quotes when systemd would otherwise split it, with the escapes systemd understands;
+// Go's own quoting would write \u escapes for some runes
+func quote(value string) string {
+	if value != "" && !strings.ContainsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune("\"'\\;", r)
+	}) {
+		return value
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			b.WriteString(fmt.Sprintf(`\x%02x`, r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+type Manager struct {
+	ServiceDir string // /lib/systemd/system
+	LinkDir    string // /etc/systemd/system/multi-user.target.wants
+	SystemCtl  *systemctl.SystemCtl
+}
+
+func New(systemCtl *systemctl.SystemCtl) *Manager {
+	return &Manager{
+		ServiceDir: constants.ServiceDir,
+		LinkDir:    constants.ServiceDirSoftLink,
+		SystemCtl:  systemCtl,
+	}
+}
+
+func (inst *Manager) ServiceFile(serviceName string) string {
+	return path.Join(inst.ServiceDir, serviceName)
+}
+
+func (inst *Manager) linkFile(serviceName string) string {
+	return path.Join(inst.LinkDir, serviceName)
+}
+
+func (inst *Manager) Exists(serviceName string) bool {
+	_, err := os.Stat(inst.ServiceFile(serviceName))
+	return err == nil
+}
+
+// Install writes the service file, links it, reloads the daemon and then enables & (re)starts the service
+func (inst *Manager) Install(serviceName string, unit *Unit) error {
+	serviceFile := inst.ServiceFile(serviceName)
+	if err := os.WriteFile(serviceFile, []byte(unit.Render()), 0644); err != nil {
+		return errors.New(fmt.Sprintf("failed to write service file %s: %s", serviceFile, err.Error()))
+	}
+	linkFile := inst.linkFile(serviceName)
+	_ = syscall.Unlink(linkFile)
+	if err := syscall.Symlink(serviceFile, linkFile); err != nil {
+		return errors.New(fmt.Sprintf("failed to link service file %s: %s", linkFile, err.Error()))
+	}
+	if err := inst.SystemCtl.DaemonReload(); err != nil {
+		return err
+	}
+	if err := inst.SystemCtl.Enable(serviceName); err != nil {
+		return err
+	}
+	return inst.SystemCtl.Restart(serviceName)
+}
+
+// Uninstall reverses Install, a service which doesn't exist is not an error
+func (inst *Manager) Uninstall(serviceName string) error {
+	if !inst.Exists(serviceName) {
+		return nil
+	}
+	_ = inst.SystemCtl.Stop(serviceName)
+	_ = inst.SystemCtl.Disable(serviceName)
+	if err := syscall.Unlink(inst.linkFile(serviceName)); err != nil && !errors.Is(err, syscall.ENOENT) {
+		return err
+	}
+	if err := os.Remove(inst.ServiceFile(serviceName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return inst.SystemCtl.DaemonReload()
+}
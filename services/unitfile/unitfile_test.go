@@ -0,0 +1,124 @@
+package unitfile
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	unit := &Unit{
+		Description:           "rubix os",
+		WorkingDirectory:      "/data/rubix-os",
+		ExecStart:             []string{"/data/rubix-os/app", "-p", "1660", "--name", "my app"},
+		Environment:           []string{"A=1", "B=two words"},
+		Restart:               "on-failure",
+		RestartSec:            5,
+		SyslogIdentifier:      "rubix-os",
+		StartLimitIntervalSec: 60,
+		StartLimitBurst:       3,
+		MemoryMaxMB:           256,
+		CPUQuotaPercent:       50,
+		Nice:                  5,
+		LimitNOFILE:           1024,
+	}
+	expected := `[Unit]
+Description=rubix os
+After=network.target
+StartLimitIntervalSec=60
+StartLimitBurst=3
+
+[Service]
+Type=simple
+User=root
+WorkingDirectory=/data/rubix-os
+Environment=A=1
+Environment="B=two words"
+ExecStart=/data/rubix-os/app -p 1660 --name "my app"
+Restart=on-failure
+RestartSec=5
+MemoryMax=256M
+CPUQuota=50%
+Nice=5
+LimitNOFILE=1024
+StandardOutput=journal
+StandardError=journal
+SyslogIdentifier=rubix-os
+
+[Install]
+WantedBy=multi-user.target
+`
+	if got := unit.Render(); got != expected {
+		t.Fatalf("unexpected unit:\n%s", got)
+	}
+}
+
+func TestRenderDefaults(t *testing.T) {
+	expected := `[Unit]
+Description=demo
+After=network.target
+
+[Service]
+Type=simple
+User=root
+ExecStart=/usr/bin/demo
+Restart=always
+RestartSec=10
+StandardOutput=journal
+StandardError=journal
+
+[Install]
+WantedBy=multi-user.target
+`
+	if got := (&Unit{Description: "demo", ExecStart: []string{"/usr/bin/demo"}}).Render(); got != expected {
+		t.Fatalf("unexpected unit:\n%s", got)
+	}
+}
+
+func TestRenderEscapes(t *testing.T) {
+	unit := &Unit{
+		Description: "100% up",
+		ExecStart:   []string{"/bin/sh", "-c", "echo $HOME %h", ""},
+		Environment: []string{"NAME=café", "PCT=50%", "MULTI=a\nb"},
+	}
+	expected := `[Unit]
+Description=100%% up
+After=network.target
+
+[Service]
+Type=simple
+User=root
+Environment=NAME=café
+Environment=PCT=50%%
+Environment="MULTI=a\nb"
+ExecStart=/bin/sh -c "echo $$HOME %%h" ""
+Restart=always
+RestartSec=10
+StandardOutput=journal
+StandardError=journal
+
+[Install]
+WantedBy=multi-user.target
+`
+	if got := unit.Render(); got != expected {
+		t.Fatalf("unexpected unit:\n%s", got)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":     "plain",
+		"":          `""`,
+		"a b":       `"a b"`,
+		`say "hi"`:  `"say \"hi\""`,
+		`c:\dir`:    `"c:\\dir"`,
+		"it's":      `"it's"`,
+		"tab\there": `"tab\there"`,
+		"nul\x00":   `"nul\x00"`,
+		"a;b":       `"a;b"`,
+		"é":         "é",
+	}
+	for value, expected := range tests {
+		if got := quote(value); got != expected {
+			t.Errorf("%q: expected %s, got %s", value, expected, got)
+		}
+	}
+}
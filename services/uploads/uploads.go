@@ -0,0 +1,339 @@
+package uploads
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/NubeIO/platform/utils/checksum"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("upload session not found")
+	ErrOffset   = errors.New("offset doesn't match the uploaded size")
+)
+
+const (
+	metaFile = "session.json"
+	dataFile = "data"
+)
+
+// Session is a resumable upload, the chunks are appended to <Dir>/<id>/data until it's finalized
+type Session struct {
+	ID        string    `json:"id"`
+	FileName  string    `json:"fileName"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	Complete  bool      `json:"complete"`
+	Checksum  string    `json:"checksum,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type Manager struct {
+	Dir      string
+	Expiry   time.Duration // sessions which haven't been written to for this long are garbage-collected
+	FileMode os.FileMode
+	mutex    sync.Mutex
+	locks    map[string]*sync.Mutex
+}
+
+func New(dir string, expiry time.Duration) *Manager {
+	if expiry <= 0 {
+		expiry = 24 * time.Hour
+	}
+	return &Manager{
+		Dir:      dir,
+		Expiry:   expiry,
+		FileMode: 0755,
+		locks:    make(map[string]*sync.Mutex),
+	}
+}
+
+func (inst *Manager) Create(fileName string, size int64) (*Session, error) {
+	fileName = filepath.Base(fileName)
+	if fileName == "" || fileName == "." || fileName == "/" || fileName == ".." {
+		return nil, errors.New("file name can not be empty")
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be greater than 0")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	s := &Session{
+		ID:        hex.EncodeToString(id),
+		FileName:  fileName,
+		Size:      size,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := os.MkdirAll(inst.sessionDir(s.ID), inst.FileMode); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path.Join(inst.sessionDir(s.ID), dataFile))
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+	if err = inst.save(s); err != nil {
+		return nil, err
+	}
+	return inst.withExpiry(s), nil
+}
+
+func (inst *Manager) Get(id string) (*Session, error) {
+	lock := inst.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	return inst.load(id)
+}
+
+// Write appends the chunk, offset has to be the current size so that a chunk sent twice never gets duplicated
+func (inst *Manager) Write(id string, offset int64, r io.Reader) (*Session, error) {
+	return inst.write(id, offset, -1, r, nil)
+}
+
+// WriteRange appends the chunk of a `Content-Range: bytes <start>-<end>/<size>`, the range has to be within the declared
+// size and the body as long as the range
+func (inst *Manager) WriteRange(id string, start, end, size int64, r io.Reader) (*Session, error) {
+	if start < 0 || end < start {
+		return nil, errors.New(fmt.Sprintf("invalid range %d-%d", start, end))
+	}
+	return inst.write(id, start, end-start+1, r, func(s *Session) error {
+		if size != s.Size {
+			return errors.New(fmt.Sprintf("range size %d doesn't match the declared size %d", size, s.Size))
+		}
+		if end >= s.Size {
+			return errors.New(fmt.Sprintf("range end %d is past the declared size %d", end, s.Size))
+		}
+		return nil
+	})
+}
+
+// write appends r at offset, a length >= 0 is the exact chunk size
+func (inst *Manager) write(id string, offset, length int64, r io.Reader, check func(s *Session) error) (*Session, error) {
+	lock := inst.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	s, err := inst.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Complete {
+		return nil, errors.New("upload is already finalized")
+	}
+	if offset != s.Offset {
+		return s, ErrOffset
+	}
+	if check != nil {
+		if err = check(s); err != nil {
+			return s, err
+		}
+	}
+	f, err := os.OpenFile(path.Join(inst.sessionDir(id), dataFile), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// one byte more than expected, to detect a chunk which goes past the declared size or its range
+	limit := s.Size - s.Offset
+	if length >= 0 {
+		limit = length
+	}
+	written, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	rejected := err == nil && (s.Offset+written > s.Size || length >= 0 && written != length)
+	if rejected {
+		if s.Offset+written > s.Size {
+			err = errors.New(fmt.Sprintf("upload is bigger than the declared size %d", s.Size))
+		} else {
+			err = errors.New(fmt.Sprintf("chunk has %d bytes but its range has %d", written, length))
+		}
+		_ = os.Truncate(path.Join(inst.sessionDir(id), dataFile), s.Offset)
+	} else {
+		s.Offset += written
+	}
+	// an interrupted chunk keeps what was received, the client resumes from the returned offset
+	s.UpdatedAt = time.Now().UTC()
+	if saveErr := inst.save(s); err == nil {
+		err = saveErr
+	}
+	return inst.withExpiry(s), err
+}
+
+// Finalize verifies the size & the checksum, the file is then available at Path
+func (inst *Manager) Finalize(id, expectedChecksum string) (*Session, error) {
+	lock := inst.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	s, err := inst.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Complete {
+		return s, nil
+	}
+	if s.Offset != s.Size {
+		return s, errors.New(fmt.Sprintf("upload is incomplete: %d of %d bytes", s.Offset, s.Size))
+	}
+	if expectedChecksum == "" {
+		return s, errors.New("checksum can not be empty")
+	}
+	data := path.Join(inst.sessionDir(id), dataFile)
+	if err = checksum.Verify(data, expectedChecksum); err != nil {
+		return s, err
+	}
+	if err = os.Rename(data, inst.Path(s)); err != nil {
+		return s, err
+	}
+	s.Complete = true
+	s.Checksum = expectedChecksum
+	s.UpdatedAt = time.Now().UTC()
+	return inst.withExpiry(s), inst.save(s)
+}
+
+// Path is where the file is once the session is finalized
+func (inst *Manager) Path(s *Session) string {
+	return path.Join(inst.sessionDir(s.ID), s.FileName)
+}
+
+// Complete returns the path of a finalized upload
+func (inst *Manager) Complete(id string) (string, error) {
+	s, err := inst.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if !s.Complete {
+		return "", errors.New(fmt.Sprintf("upload %s is not finalized", id))
+	}
+	return inst.Path(s), nil
+}
+
+func (inst *Manager) Remove(id string) error {
+	lock := inst.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	if _, err := inst.load(id); err != nil {
+		return err
+	}
+	inst.mutex.Lock()
+	delete(inst.locks, id)
+	inst.mutex.Unlock()
+	return os.RemoveAll(inst.sessionDir(id))
+}
+
+// GC removes the expired sessions, finalized ones included as nobody picked them up
+func (inst *Manager) GC() {
+	entries, err := os.ReadDir(inst.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		inst.removeExpired(entry)
+	}
+}
+
+// removeExpired holds the session lock so that a chunk being written keeps its session
+func (inst *Manager) removeExpired(entry os.DirEntry) {
+	id := entry.Name()
+	lock := inst.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	s, err := inst.load(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return
+	}
+	if s != nil && time.Now().Before(s.ExpiresAt) {
+		return
+	}
+	if s == nil { // no session file, either broken or still being created
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < inst.Expiry {
+			return
+		}
+	}
+	log.Infof("removing expired upload session %s", id)
+	inst.mutex.Lock()
+	delete(inst.locks, id)
+	inst.mutex.Unlock()
+	_ = os.RemoveAll(inst.sessionDir(id))
+}
+
+// RunGC calls GC on every interval, it never returns
+func (inst *Manager) RunGC(interval time.Duration) {
+	for {
+		inst.GC()
+		time.Sleep(interval)
+	}
+}
+
+func (inst *Manager) sessionDir(id string) string {
+	return path.Join(inst.Dir, id)
+}
+
+func (inst *Manager) lock(id string) *sync.Mutex {
+	inst.mutex.Lock()
+	defer inst.mutex.Unlock()
+	l, found := inst.locks[id]
+	if !found {
+		l = &sync.Mutex{}
+		inst.locks[id] = l
+	}
+	return l
+}
+
+func (inst *Manager) withExpiry(s *Session) *Session {
+	s.ExpiresAt = s.UpdatedAt.Add(inst.Expiry)
+	return s
+}
+
+// load trusts the size of the data file over the stored offset, the process might have died in the middle of a chunk
+func (inst *Manager) load(id string) (*Session, error) {
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(path.Join(inst.sessionDir(id), metaFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if !s.Complete {
+		info, err := os.Stat(path.Join(inst.sessionDir(id), dataFile))
+		if err != nil {
+			return nil, err
+		}
+		s.Offset = info.Size()
+	}
+	return inst.withExpiry(s), nil
+}
+
+func (inst *Manager) save(s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path.Join(inst.sessionDir(s.ID), metaFile+".tmp")
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path.Join(inst.sessionDir(s.ID), metaFile))
+}
@@ -0,0 +1,126 @@
+package uploads
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestResume(t *testing.T) {
+	m := New(t.TempDir(), time.Hour)
+	content := []byte("hello resumable world")
+	s, err := m.Create("app.zip", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Write(s.ID, 0, bytes.NewReader(content[:5])); err != nil {
+		t.Fatal(err)
+	}
+	// a retried chunk with a stale offset is rejected
+	if _, err = m.Write(s.ID, 0, bytes.NewReader(content[:5])); !errors.Is(err, ErrOffset) {
+		t.Fatalf("expected an offset error, got %v", err)
+	}
+	s, err = m.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Write(s.ID, s.Offset, bytes.NewReader(content[s.Offset:])); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	if _, err = m.Finalize(s.ID, "sha256:"+hex.EncodeToString(make([]byte, 32))); err == nil {
+		t.Fatal("expected a checksum mismatch")
+	}
+	s, err = m.Finalize(s.ID, "sha256:"+hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(m.Path(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("expected %q, got %q", content, data)
+	}
+}
+
+func TestGC(t *testing.T) {
+	m := New(t.TempDir(), time.Hour)
+	s, err := m.Create("app.zip", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.GC()
+	if _, err = m.Get(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	m.Expiry = -time.Second
+	m.GC()
+	if _, err = m.Get(s.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the session to be collected, got %v", err)
+	}
+}
+
+func TestWriteRange(t *testing.T) {
+	m := New(t.TempDir(), time.Hour)
+	content := []byte("0123456789")
+	s, err := m.Create("app.zip", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name            string
+		start, end, len int64
+		body            []byte
+	}{
+		{"size doesn't match", 0, 4, 20, content[:5]},
+		{"end past the size", 5, 10, 10, content[5:]},
+		{"body shorter than the range", 0, 4, 10, content[:3]},
+		{"body longer than the range", 0, 4, 10, content[:7]},
+	} {
+		if _, err = m.WriteRange(s.ID, test.start, test.end, test.len, bytes.NewReader(test.body)); err == nil {
+			t.Fatalf("%s: expected an error", test.name)
+		}
+		if s, err = m.Get(s.ID); err != nil || s.Offset != 0 {
+			t.Fatalf("%s: expected nothing written, got offset %d, %v", test.name, s.Offset, err)
+		}
+	}
+	if _, err = m.WriteRange(s.ID, 0, 4, 10, bytes.NewReader(content[:5])); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = m.WriteRange(s.ID, 5, 9, 10, bytes.NewReader(content[5:])); err != nil || s.Offset != 10 {
+		t.Fatalf("expected offset 10, got %d, %v", s.Offset, err)
+	}
+}
+
+func TestGCWaitsForWrites(t *testing.T) {
+	m := New(t.TempDir(), time.Hour)
+	s, err := m.Create("app.zip", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Expiry = -time.Second
+	lock := m.lock(s.ID)
+	lock.Lock()
+	done := make(chan struct{})
+	go func() {
+		m.GC()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if _, err = os.Stat(m.sessionDir(s.ID)); err != nil {
+		t.Fatalf("expected the session to be kept while it's locked, got %v", err)
+	}
+	lock.Unlock()
+	<-done
+	if _, err = os.Stat(m.sessionDir(s.ID)); !os.IsNotExist(err) {
+		t.Fatalf("expected the session to be collected, got %v", err)
+	}
+	if len(m.locks) != 0 {
+		t.Fatalf("expected the session lock to be dropped, got %d", len(m.locks))
+	}
+}
@@ -0,0 +1,148 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	SHA256 = "sha256"
+	SHA1   = "sha1"
+	MD5    = "md5"
+)
+
+func New(algo string) (hash.Hash, error) {
+	switch strings.ToLower(algo) {
+	case SHA256, "":
+		return sha256.New(), nil
+	case SHA1:
+		return sha1.New(), nil
+	case MD5:
+		return md5.New(), nil
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported checksum algo %s, try sha256, sha1 or md5", algo))
+}
+
+// Parse accepts `<algo>:<hex>` or a bare hex digest, in which case the algo is picked from the length
+func Parse(value string) (algo, digest string, err error) {
+	value = strings.TrimSpace(value)
+	if parts := strings.SplitN(value, ":", 2); len(parts) == 2 {
+		algo, digest = strings.ToLower(parts[0]), strings.ToLower(parts[1])
+	} else {
+		digest = strings.ToLower(value)
+		switch len(digest) {
+		case 64:
+			algo = SHA256
+		case 40:
+			algo = SHA1
+		case 32:
+			algo = MD5
+		default:
+			return "", "", errors.New(fmt.Sprintf("can not detect checksum algo of %s", value))
+		}
+	}
+	if _, err = hex.DecodeString(digest); err != nil {
+		return "", "", errors.New(fmt.Sprintf("invalid checksum %s", value))
+	}
+	if _, err = New(algo); err != nil {
+		return "", "", err
+	}
+	return algo, digest, nil
+}
+
+func Reader(r io.Reader, algo string) (string, error) {
+	h, err := New(algo)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func File(filePath, algo string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return Reader(f, algo)
+}
+
+// SidecarSuffix is the sha256sum file kept next to a file
+const SidecarSuffix = ".sha256"
+
+func SidecarPath(filePath string) string {
+	return filePath + SidecarSuffix
+}
+
+func IsSidecar(name string) bool {
+	return strings.HasSuffix(name, SidecarSuffix)
+}
+
+// WriteSidecar stores the sha256 of the file next to it in the sha256sum format: <hex>  <file name>
+func WriteSidecar(filePath string) (string, error) {
+	digest, err := File(filePath, SHA256)
+	if err != nil {
+		return "", err
+	}
+	content := fmt.Sprintf("%s  %s\n", digest, filepath.Base(filePath))
+	if err = os.WriteFile(SidecarPath(filePath), []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", SHA256, digest), nil
+}
+
+// Verify compares the file against an expected value in the format accepted by Parse
+func Verify(filePath, expected string) error {
+	algo, digest, err := Parse(expected)
+	if err != nil {
+		return err
+	}
+	actual, err := File(filePath, algo)
+	if err != nil {
+		return err
+	}
+	if actual != digest {
+		return errors.New(fmt.Sprintf("%s checksum mismatch for %s: expected %s, got %s", algo, filePath, digest, actual))
+	}
+	return nil
+}
+
+// WriteVerified has write fill a temp file next to filePath, which only gets its name once it matches expected
+// (nothing is verified when it's empty), so a failed or rejected write leaves nothing behind
+func WriteVerified(filePath, expected string, mode os.FileMode, write func(tmpFile string) error) error {
+	if expected != "" {
+		if _, _, err := Parse(expected); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), fmt.Sprintf(".%s.upload-*", filepath.Base(filePath)))
+	if err != nil {
+		return err
+	}
+	_ = tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err = write(tmp.Name()); err != nil {
+		return err
+	}
+	if expected != "" {
+		if err = Verify(tmp.Name(), expected); err != nil {
+			return err
+		}
+	}
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
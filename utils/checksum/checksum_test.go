@@ -0,0 +1,135 @@
+package checksum
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// sha256 of "hello"
+const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func writeFile(t *testing.T, content string) string {
+	filePath := path.Join(t.TempDir(), "app.zip")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value, algo, digest string
+		valid               bool
+	}{
+		{"sha256:" + helloSHA256, SHA256, helloSHA256, true},
+		{"SHA256:" + strings.ToUpper(helloSHA256), SHA256, helloSHA256, true},
+		{helloSHA256, SHA256, helloSHA256, true},
+		{"aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", SHA1, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", true},
+		{"md5:5d41402abc4b2a76b9719d911017c592", MD5, "5d41402abc4b2a76b9719d911017c592", true},
+		{"abc", "", "", false},
+		{"sha256:not-hex", "", "", false},
+		{"crc32:00000000", "", "", false},
+	}
+	for _, test := range tests {
+		algo, digest, err := Parse(test.value)
+		if (err == nil) != test.valid || algo != test.algo || digest != test.digest {
+			t.Errorf("%s: expected %s %s valid %v, got %s %s %v", test.value, test.algo, test.digest, test.valid, algo, digest, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	filePath := writeFile(t, "hello")
+	for _, expected := range []string{"sha256:" + helloSHA256, helloSHA256, "md5:5d41402abc4b2a76b9719d911017c592"} {
+		if err := Verify(filePath, expected); err != nil {
+			t.Errorf("%s: %s", expected, err)
+		}
+	}
+	err := Verify(filePath, "sha256:"+strings.Repeat("0", 64))
+	if err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Fatalf("expected a mismatch, got %v", err)
+	}
+	if err = Verify(path.Join(t.TempDir(), "missing"), helloSHA256); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	filePath := writeFile(t, "hello")
+	value, err := WriteSidecar(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "sha256:"+helloSHA256 {
+		t.Fatalf("unexpected checksum %s", value)
+	}
+	data, err := os.ReadFile(SidecarPath(filePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != helloSHA256+"  app.zip\n" {
+		t.Fatalf("expected the sha256sum format, got %q", data)
+	}
+	if !IsSidecar(SidecarPath(filePath)) || IsSidecar(filePath) || IsSidecar("app.sha256.zip") {
+		t.Fatal("unexpected IsSidecar")
+	}
+}
+
+func TestWriteVerified(t *testing.T) {
+	write := func(content string) func(tmpFile string) error {
+		return func(tmpFile string) error {
+			return os.WriteFile(tmpFile, []byte(content), 0600)
+		}
+	}
+	tests := []struct {
+		name     string
+		expected string
+		write    func(tmpFile string) error
+		written  bool
+	}{
+		{"verified", "sha256:" + helloSHA256, write("hello"), true},
+		{"not verified", "", write("hello"), true},
+		{"mismatch", "sha256:" + helloSHA256, write("tampered"), false},
+		{"invalid checksum", "sha256:xyz", write("hello"), false},
+		{"failed write", "", func(tmpFile string) error { return errors.New("connection reset") }, false},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		filePath := path.Join(dir, "app.zip")
+		err := WriteVerified(filePath, test.expected, 0640, test.write)
+		if (err == nil) != test.written {
+			t.Errorf("%s: expected written %v, got %v", test.name, test.written, err)
+			continue
+		}
+		entries, _ := os.ReadDir(dir)
+		if !test.written {
+			if len(entries) != 0 {
+				t.Errorf("%s: expected a rejected file to leave nothing behind, got %v", test.name, entries)
+			}
+			continue
+		}
+		info, err := os.Stat(filePath)
+		if err != nil || len(entries) != 1 {
+			t.Errorf("%s: expected only the file, got %v %v", test.name, entries, err)
+			continue
+		}
+		if info.Mode().Perm() != 0640 {
+			t.Errorf("%s: expected mode 0640, got %s", test.name, info.Mode())
+		}
+	}
+}
+
+func TestWriteVerifiedKeepsExistingFileOnMismatch(t *testing.T) {
+	filePath := writeFile(t, "old")
+	if err := WriteVerified(filePath, helloSHA256, 0644, func(tmpFile string) error {
+		return os.WriteFile(tmpFile, []byte("tampered"), 0644)
+	}); err == nil {
+		t.Fatal("expected a mismatch")
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != "old" {
+		t.Fatalf("expected the existing file to be kept, got %q", data)
+	}
+}
@@ -0,0 +1,125 @@
+package tail
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	Truncated = "truncated"
+	Replaced  = "replaced"
+)
+
+type Event struct {
+	Data    []byte
+	Offset  int64  // where the next read starts, can be used to resume
+	Rotated string // truncated or replaced, Data is empty then and Offset is back to 0
+	Ready   bool   // the first event, sent once the file is open, Offset is where following starts
+}
+
+// Follow calls fn with a ready event once the file is open, then with the bytes appended to the file from offset on, a negative offset starts at the end.
+// A truncated file is read again from the start, a replaced one (logrotate create) is reopened once the old one is drained,
+// a missing file is waited for since logrotate renames first and creates the new file after
+func Follow(ctx context.Context, filePath string, offset int64, interval time.Duration, fn func(e *Event) error) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if offset < 0 {
+		offset = info.Size()
+	}
+	if err = fn(&Event{Offset: offset, Ready: true}); err != nil {
+		return err
+	}
+	chunk := make([]byte, chunkSize)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := f.ReadAt(chunk, offset)
+			if n > 0 {
+				offset += int64(n)
+				if err := fn(&Event{Data: chunk[:n], Offset: offset}); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF || n == 0 {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		info, err = f.Stat()
+		if err != nil {
+			return err
+		}
+		if info.Size() < offset {
+			offset = 0
+			if err = fn(&Event{Offset: offset, Rotated: Truncated}); err != nil {
+				return err
+			}
+			continue
+		}
+		if current, err := os.Stat(filePath); err == nil && !os.SameFile(info, current) {
+			replaced, err := os.Open(filePath)
+			if err == nil {
+				_ = f.Close()
+				f, offset = replaced, 0
+				if err = fn(&Event{Offset: offset, Rotated: Replaced}); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Range returns a reader of length bytes from offset, a negative offset counts back from the end and length 0 reads to the end
+func Range(filePath string, offset, length int64) (io.ReadCloser, int64, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, 0, err
+	}
+	size := info.Size()
+	if offset < 0 {
+		offset += size
+		if offset < 0 {
+			offset = 0
+		}
+	}
+	if offset > size {
+		offset = size
+	}
+	if length <= 0 || offset+length > size {
+		length = size - offset
+	}
+	reader := io.NewSectionReader(f, offset, length)
+	return &rangeReader{Reader: reader, file: f}, offset, length, nil
+}
+
+type rangeReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (r *rangeReader) Close() error {
+	return r.file.Close()
+}
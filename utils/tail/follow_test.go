@@ -0,0 +1,220 @@
+package tail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+type step struct {
+	do     func(t *testing.T, file string)
+	expect []string // ready@<offset>, data:<data>@<offset> or rotated:<kind>@<offset>
+}
+
+func write(content string) func(t *testing.T, file string) {
+	return func(t *testing.T, file string) {
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err = f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func rename(t *testing.T, file string) {
+	if err := os.Rename(file, file+".1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectEvents(t *testing.T, events chan string, expect ...string) {
+	for _, expected := range expect {
+		select {
+		case got := <-events:
+			if got != expected {
+				t.Fatalf("expected %q, got %q", expected, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", expected)
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		offset  int64
+		ready   int64
+		steps   []step
+	}{
+		{
+			name:    "negative offset starts at the end",
+			content: "old\n",
+			offset:  -1,
+			ready:   4,
+			steps:   []step{{do: write("new\n"), expect: []string{"data:new\n@8"}}},
+		},
+		{
+			name:    "offset zero reads the file first",
+			content: "old\n",
+			offset:  0,
+			steps: []step{
+				{expect: []string{"data:old\n@4"}},
+				{do: write("new\n"), expect: []string{"data:new\n@8"}},
+			},
+		},
+		{
+			name:    "an offset past the end is a truncated file",
+			content: "old\n",
+			offset:  100,
+			ready:   100,
+			steps:   []step{{expect: []string{"rotated:truncated@0", "data:old\n@4"}}},
+		},
+		{
+			name:    "truncated",
+			content: "old line\n",
+			offset:  -1,
+			ready:   9,
+			steps: []step{
+				{do: func(t *testing.T, file string) {
+					if err := os.Truncate(file, 0); err != nil {
+						t.Fatal(err)
+					}
+				}, expect: []string{"rotated:truncated@0"}},
+				{do: write("new\n"), expect: []string{"data:new\n@4"}},
+			},
+		},
+		{
+			name:    "replaced after the old file is drained",
+			content: "",
+			offset:  0,
+			steps: []step{{do: func(t *testing.T, file string) {
+				write("last\n")(t, file)
+				rename(t, file)
+				write("first\n")(t, file)
+			}, expect: []string{"data:last\n@5", "rotated:replaced@0", "data:first\n@6"}}},
+		},
+		{
+			name:    "waits for the file to be created again",
+			content: "old\n",
+			offset:  -1,
+			ready:   4,
+			steps: []step{
+				{do: func(t *testing.T, file string) {
+					rename(t, file)
+					time.Sleep(20 * time.Millisecond) // a few polls without a file
+				}},
+				{do: write("new\n"), expect: []string{"rotated:replaced@0", "data:new\n@4"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := path.Join(t.TempDir(), "app.log")
+			write(test.content)(t, file)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events := make(chan string, 100)
+			done := make(chan error, 1)
+			go func() {
+				done <- Follow(ctx, file, test.offset, time.Millisecond, func(e *Event) error {
+					if e.Ready {
+						events <- fmt.Sprintf("ready@%d", e.Offset)
+					} else if e.Rotated != "" {
+						events <- fmt.Sprintf("rotated:%s@%d", e.Rotated, e.Offset)
+					} else {
+						events <- fmt.Sprintf("data:%s@%d", e.Data, e.Offset)
+					}
+					return nil
+				})
+			}()
+			// nothing is written before the file is open, a negative offset would skip it
+			expectEvents(t, events, fmt.Sprintf("ready@%d", test.ready))
+			for _, s := range test.steps {
+				if s.do != nil {
+					s.do(t, file)
+				}
+				expectEvents(t, events, s.expect...)
+			}
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Fatalf("expected the follow to stop with the context, got %v", err)
+			}
+			select {
+			case got := <-events:
+				t.Fatalf("unexpected event %q", got)
+			default:
+			}
+		})
+	}
+}
+
+func TestFollowMissingFile(t *testing.T) {
+	err := Follow(context.Background(), path.Join(t.TempDir(), "missing.log"), 0, time.Millisecond, func(e *Event) error {
+		return nil
+	})
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestFollowStopsOnCallbackError(t *testing.T) {
+	file := path.Join(t.TempDir(), "app.log")
+	write("old\n")(t, file)
+	stop := fmt.Errorf("stop")
+	var events int
+	err := Follow(context.Background(), file, 0, time.Millisecond, func(e *Event) error {
+		if events++; !e.Ready {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || events != 2 {
+		t.Fatalf("expected the callback error after the ready event, got %v after %d events", err, events)
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		offset, length int64
+		data           string
+		start          int64
+	}{
+		{0, 0, "0123456789", 0},
+		{2, 3, "234", 2},
+		{8, 5, "89", 8},
+		{-3, 0, "789", 7},
+		{-3, 2, "78", 7},
+		{-20, 0, "0123456789", 0},
+		{-20, 4, "0123", 0},
+		{20, 0, "", 10},
+	}
+	file := path.Join(t.TempDir(), "range.log")
+	write("0123456789")(t, file)
+	for _, test := range tests {
+		reader, start, length, err := Range(file, test.offset, test.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.data || start != test.start || length != int64(len(test.data)) {
+			t.Errorf("offset %d length %d: expected %q at %d, got %q at %d with length %d",
+				test.offset, test.length, test.data, test.start, data, start, length)
+		}
+	}
+	if _, _, _, err := Range(path.Join(t.TempDir(), "missing.log"), 0, 0); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,53 @@
+package tail
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+const chunkSize = 32 * 1024
+
+// Lines returns the last n lines of the file, it reads the file backwards so big files are fine
+func Lines(filePath string, n int) ([]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadLines(f, info.Size(), n)
+}
+
+// ReadLines returns the last n lines out of the first size bytes of r
+func ReadLines(r io.ReaderAt, size int64, n int) ([]string, error) {
+	if n <= 0 || size == 0 {
+		return []string{}, nil
+	}
+	var data []byte
+	offset := size
+	for offset > 0 && bytes.Count(data, []byte("\n")) <= n {
+		readSize := int64(chunkSize)
+		if offset < readSize {
+			readSize = offset
+		}
+		offset -= readSize
+		chunk := make([]byte, readSize)
+		if _, err := r.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data = append(chunk, data...)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if offset > 0 {
+		lines = lines[1:] // the first line is most likely partial
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
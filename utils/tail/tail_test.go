@@ -0,0 +1,52 @@
+package tail
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		content string
+		n       int
+		lines   []string
+	}{
+		{"", 10, []string{}},
+		{"a\nb\nc\n", 0, []string{}},
+		{"a\nb\nc\n", 2, []string{"b", "c"}},
+		{"a\nb\nc", 2, []string{"b", "c"}},
+		{"a\nb\nc\n", 10, []string{"a", "b", "c"}},
+	}
+	for _, test := range tests {
+		lines, err := ReadLines(strings.NewReader(test.content), int64(len(test.content)), test.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%q last %d: expected %q, got %q", test.content, test.n, test.lines, lines)
+		}
+	}
+}
+
+func TestLinesAcrossChunks(t *testing.T) {
+	line := strings.Repeat("x", 1000)
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("last\n")
+	file := path.Join(t.TempDir(), "big.log")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := Lines(file, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 40 || lines[39] != "last" || lines[0] != line {
+		t.Fatalf("unexpected lines: %d, last %q", len(lines), lines[len(lines)-1])
+	}
+}